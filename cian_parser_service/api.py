@@ -2,8 +2,13 @@
 REST API for the CIAN Parser microservice.
 """
 
-from flask import Flask, jsonify, request
+import uuid
+
+from flask import Flask, g, jsonify, request
 from .logger import logger
+from .tracing import setup_tracing
+
+REQUEST_ID_HEADER = 'X-Request-ID'
 
 class CianAPI:
     """API for CIAN Parser microservice."""
@@ -17,6 +22,7 @@ class CianAPI:
             cian_cache: Instance of CianCache.
         """
         self.app = Flask(__name__)
+        setup_tracing(self.app)
         self.cian_service = cian_service
         self.cian_cache = cian_cache
         
@@ -26,6 +32,17 @@ class CianAPI:
     def _register_routes(self):
         """Register API routes."""
         
+        @self.app.before_request
+        def assign_request_id():
+            """Reuse the caller's request ID so logs correlate across services."""
+            g.request_id = request.headers.get(REQUEST_ID_HEADER) or uuid.uuid4().hex[:16]
+        
+        @self.app.after_request
+        def add_request_id_header(response):
+            """Echo the request ID back to the caller."""
+            response.headers[REQUEST_ID_HEADER] = g.get('request_id', '')
+            return response
+        
         @self.app.route('/listings', methods=['GET'])
         def get_listings():
             """API endpoint to get listings."""
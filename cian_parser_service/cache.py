@@ -6,6 +6,7 @@ import time
 import threading
 from .config import CACHE_REFRESH_INTERVAL
 from .logger import logger
+from .tracing import span
 
 class CianCache:
     """Cache for CIAN listings data."""
@@ -43,7 +44,8 @@ class CianCache:
                  time.time() - self.cache["last_updated"] > CACHE_REFRESH_INTERVAL)):
                 try:
                     logger.info("Cache miss or force refresh, fetching new data")
-                    self.cache["listings"] = self.cian_service.get_listings()
+                    with span('cian.fetch_listings'):
+                        self.cache["listings"] = self.cian_service.get_listings()
                     self.cache["last_updated"] = time.time()
                 except Exception as e:
                     logger.error(f"Error refreshing cache: {e}")
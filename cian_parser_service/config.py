@@ -2,6 +2,7 @@
 Configuration settings for the CIAN Parser microservice.
 """
 import logging
+import os
 
 # Default CIAN search settings
 CIAN_SETTINGS = {
@@ -22,7 +23,11 @@ CACHE_REFRESH_INTERVAL = 1800  # 30 minutes in seconds
 
 # Logging settings
 LOG_LEVEL = logging.INFO
-LOG_FORMAT = '%(asctime)s - %(name)s - %(levelname)s - %(message)s'
+LOG_FORMAT = os.environ.get('LOG_FORMAT', 'text')  # "text" or "json"
 LOG_FILE = 'logs/cian_parser_service.log'
 LOG_MAX_BYTES = 10485760  # 10MB
 LOG_BACKUP_COUNT = 5
+
+# Tracing settings
+TRACING_EXPORTER = os.environ.get('TRACING_EXPORTER', 'none')  # "none", "stdout" or "otlp"
+TRACING_ENDPOINT = os.environ.get('TRACING_ENDPOINT', '')  # OTLP/HTTP traces URL
@@ -2,6 +2,7 @@
 Logging configuration for the CIAN Parser microservice.
 """
 
+import json
 import logging
 import sys
 from logging.handlers import RotatingFileHandler
@@ -11,12 +12,53 @@ os.makedirs('logs', exist_ok=True)
 
 logger = logging.getLogger('cian_parser_service')
 
-def setup_logging(log_level=logging.INFO):
+TEXT_FORMAT = '%(asctime)s - %(name)s - %(levelname)s - [%(request_id)s] - %(message)s'
+
+
+class RequestIDFilter(logging.Filter):
+    """Adds the X-Request-ID and trace ID of the current Flask request to log records."""
+
+    def filter(self, record):
+        record.request_id = '-'
+        record.trace_id = '-'
+        try:
+            from .tracing import current_trace_id
+            record.trace_id = current_trace_id()
+        except ImportError:
+            pass
+        try:
+            from flask import g, has_request_context
+            if has_request_context():
+                record.request_id = g.get('request_id', '-')
+        except ImportError:
+            pass
+        return True
+
+
+class JSONFormatter(logging.Formatter):
+    """Formats log records as single-line JSON objects."""
+
+    def format(self, record):
+        entry = {
+            'time': self.formatTime(record),
+            'level': record.levelname.lower(),
+            'logger': record.name,
+            'msg': record.getMessage(),
+            'request_id': getattr(record, 'request_id', '-'),
+            'trace_id': getattr(record, 'trace_id', '-'),
+        }
+        if record.exc_info:
+            entry['error'] = self.formatException(record.exc_info)
+        return json.dumps(entry, ensure_ascii=False)
+
+
+def setup_logging(log_level=logging.INFO, log_format='text'):
     """
     Set up logging configuration.
     
     Args:
         log_level: The logging level to use.
+        log_format (str): "text" or "json".
     """
     logger.setLevel(log_level)
     logger.propagate = False
@@ -24,12 +66,16 @@ def setup_logging(log_level=logging.INFO):
     if logger.handlers:
         logger.handlers.clear()
     
+    if log_format == 'json':
+        formatter = JSONFormatter()
+    else:
+        formatter = logging.Formatter(TEXT_FORMAT)
+    request_id_filter = RequestIDFilter()
+    
     console_handler = logging.StreamHandler(sys.stdout)
     console_handler.setLevel(log_level)
-    console_format = logging.Formatter(
-        '%(asctime)s - %(name)s - %(levelname)s - %(message)s'
-    )
-    console_handler.setFormatter(console_format)
+    console_handler.setFormatter(formatter)
+    console_handler.addFilter(request_id_filter)
     
     file_handler = RotatingFileHandler(
         'logs/cian_parser_service.log',
@@ -37,10 +83,8 @@ def setup_logging(log_level=logging.INFO):
         backupCount=5
     )
     file_handler.setLevel(log_level)
-    file_format = logging.Formatter(
-        '%(asctime)s - %(name)s - %(levelname)s - %(message)s'
-    )
-    file_handler.setFormatter(file_format)
+    file_handler.setFormatter(formatter)
+    file_handler.addFilter(request_id_filter)
     
     logger.addHandler(console_handler)
     logger.addHandler(file_handler)
@@ -6,7 +6,7 @@ import os
 import signal
 import sys
 
-from .config import API_HOST, DEFAULT_PORT, LOG_LEVEL
+from .config import API_HOST, DEFAULT_PORT, LOG_FORMAT, LOG_LEVEL
 from .cian_service import CianService
 from .cache import CianCache
 from .api import CianAPI
@@ -14,7 +14,7 @@ from .logger import setup_logging
 
 def main():
     """Run the CIAN Parser microservice."""
-    logger = setup_logging(LOG_LEVEL)
+    logger = setup_logging(LOG_LEVEL, LOG_FORMAT)
     
     cian_service = CianService()
     cian_cache = CianCache(cian_service)
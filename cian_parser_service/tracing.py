@@ -0,0 +1,74 @@
+"""
+OpenTelemetry tracing for the CIAN Parser microservice.
+
+Incoming requests continue the trace started by the Telegram bot (W3C
+traceparent header). Tracing is disabled unless TRACING_EXPORTER is set to
+"stdout" or "otlp".
+"""
+
+from contextlib import contextmanager
+
+from .config import TRACING_ENDPOINT, TRACING_EXPORTER
+from .logger import logger
+
+SERVICE_NAME = 'cian_parser_service'
+
+try:
+    from opentelemetry import trace
+except ImportError:  # tracing is optional
+    trace = None
+
+
+def setup_tracing(app):
+    """
+    Configure the tracer provider and instrument the Flask app.
+    
+    Args:
+        app: The Flask application to instrument.
+    """
+    if TRACING_EXPORTER in ('', 'none'):
+        return
+    if trace is None:
+        logger.warning("TRACING_EXPORTER is set but opentelemetry is not installed")
+        return
+    
+    from opentelemetry.instrumentation.flask import FlaskInstrumentor
+    from opentelemetry.sdk.resources import Resource
+    from opentelemetry.sdk.trace import TracerProvider
+    from opentelemetry.sdk.trace.export import BatchSpanProcessor, ConsoleSpanExporter
+    
+    if TRACING_EXPORTER == 'stdout':
+        exporter = ConsoleSpanExporter()
+    elif TRACING_EXPORTER == 'otlp':
+        from opentelemetry.exporter.otlp.proto.http.trace_exporter import OTLPSpanExporter
+        exporter = OTLPSpanExporter(endpoint=TRACING_ENDPOINT) if TRACING_ENDPOINT else OTLPSpanExporter()
+    else:
+        logger.warning(f"Unknown tracing exporter {TRACING_EXPORTER!r}, tracing disabled")
+        return
+    
+    provider = TracerProvider(resource=Resource.create({'service.name': SERVICE_NAME}))
+    provider.add_span_processor(BatchSpanProcessor(exporter))
+    trace.set_tracer_provider(provider)
+    
+    FlaskInstrumentor().instrument_app(app)
+    logger.info(f"Tracing enabled with {TRACING_EXPORTER} exporter")
+
+
+@contextmanager
+def span(name):
+    """Open a child span of the current request span, if tracing is available."""
+    if trace is None:
+        yield
+        return
+    with trace.get_tracer(SERVICE_NAME).start_as_current_span(name):
+        yield
+
+
+def current_trace_id():
+    """Return the hex trace ID of the current span, or '-' if there is none."""
+    if trace is None:
+        return '-'
+    context = trace.get_current_span().get_span_context()
+    if not context.is_valid:
+        return '-'
+    return format(context.trace_id, '032x')
@@ -0,0 +1,56 @@
+#!/bin/bash
+
+# ЦИАН Telegram Notifier Fake Webhook Update Script
+#
+# Posts a fake Telegram update to the bot running in webhook mode, so the
+# webhook path can be checked locally, e.g. with TELEGRAM_API_URL pointing
+# at a fake Bot API server.
+
+TEXT="${1:-/start}"
+USER_ID="${USER_ID:-100000001}"
+WEBHOOK_ENDPOINT="${WEBHOOK_ENDPOINT:-http://localhost:8080/telegram/webhook}"
+
+if [ -f "docker.env" ] && [ -z "$WEBHOOK_SECRET" ]; then
+    WEBHOOK_SECRET=$(grep -E '^WEBHOOK_SECRET=' docker.env | cut -d= -f2-)
+fi
+
+UPDATE_ID=$(date +%s)
+COMMAND_LENGTH=0
+if [[ "$TEXT" == /* ]]; then
+    COMMAND=${TEXT%% *}
+    COMMAND_LENGTH=${#COMMAND}
+fi
+
+ENTITIES="[]"
+if [ "$COMMAND_LENGTH" -gt 0 ]; then
+    ENTITIES="[{\"type\":\"bot_command\",\"offset\":0,\"length\":$COMMAND_LENGTH}]"
+fi
+
+PAYLOAD=$(cat <<JSON
+{
+  "update_id": $UPDATE_ID,
+  "message": {
+    "message_id": $UPDATE_ID,
+    "date": $UPDATE_ID,
+    "text": "$TEXT",
+    "entities": $ENTITIES,
+    "from": {"id": $USER_ID, "is_bot": false, "first_name": "Test", "username": "test_user"},
+    "chat": {"id": $USER_ID, "type": "private", "first_name": "Test", "username": "test_user"}
+  }
+}
+JSON
+)
+
+echo "📨 Отправка обновления \"$TEXT\" на $WEBHOOK_ENDPOINT..."
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$WEBHOOK_ENDPOINT" \
+    -H "Content-Type: application/json" \
+    -H "X-Telegram-Bot-Api-Secret-Token: $WEBHOOK_SECRET" \
+    -d "$PAYLOAD")
+
+if [ "$STATUS" = "200" ]; then
+    echo "✅ Обновление принято"
+else
+    echo "❌ Бот ответил статусом $STATUS"
+    exit 1
+fi
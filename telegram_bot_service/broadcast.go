@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"telegram_bot_service/internal/bot"
+	"telegram_bot_service/internal/config"
+	"unicode/utf8"
+)
+
+const broadcastUsage = "usage: telegram_bot_service broadcast --file msg.md [--dry-run]"
+
+// maxMessageLength is Telegram's limit on the text of a message
+const maxMessageLength = 4096
+
+// runBroadcast implements the broadcast subcommand
+func runBroadcast(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("broadcast", flag.ContinueOnError)
+	file := flags.String("file", "", "Markdown file with the message text")
+	dryRun := flags.Bool("dry-run", false, "show the message and recipients without sending")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" || flags.NArg() > 0 {
+		return errors.New(broadcastUsage)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return fmt.Errorf("%s is empty", *file)
+	}
+	if length := utf8.RuneCountInString(text); length > maxMessageLength {
+		return fmt.Errorf("message is %d characters long, Telegram allows %d", length, maxMessageLength)
+	}
+
+	app, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if *dryRun {
+		users, err := app.users.GetAllActiveUsers(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("would send to %d active users:\n\n%s\n", len(users), text)
+		return nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	telegramBot, err := app.newBot(cfg, bot.Options{APIEndpoint: cfg.TelegramAPIURL})
+	if err != nil {
+		return fmt.Errorf("create bot: %w", err)
+	}
+
+	report, err := telegramBot.Broadcast(ctx, text, func(report *bot.BroadcastReport) {
+		fmt.Fprintf(os.Stderr, "sent %d/%d, failed %d\n", report.Sent+report.Failed, report.Total, report.Failed)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("recipients: %d\ndelivered: %d\nfailed: %d\n", report.Total, report.Sent, report.Failed)
+	reasons := make([]string, 0, len(report.Failures))
+	for reason := range report.Failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Printf("  %s: %d\n", reason, report.Failures[reason])
+	}
+	return nil
+}
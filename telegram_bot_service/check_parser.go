@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"telegram_bot_service/internal/config"
+	"telegram_bot_service/internal/services"
+	"time"
+)
+
+// checkParserTimeout bounds the whole check; the parser may scrape CIAN on a cold cache
+const checkParserTimeout = 2 * time.Minute
+
+// runCheckParser implements the check-parser subcommand
+func runCheckParser(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return errors.New("usage: telegram_bot_service check-parser")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkParserTimeout)
+	defer cancel()
+
+	cian := services.NewCianService(cfg.CianAPIURL)
+	fmt.Printf("parser: %s\n", cfg.CianAPIURL)
+	failed := false
+
+	if err := cian.HealthCheck(ctx); err != nil {
+		fmt.Printf("health: FAIL (%v)\n", err)
+		failed = true
+	} else {
+		fmt.Println("health: ok")
+	}
+
+	if settings, err := cian.GetSettings(ctx); err != nil {
+		fmt.Printf("settings: FAIL (%v)\n", err)
+		failed = true
+	} else {
+		fmt.Printf("settings: ok (%d keys)\n", len(settings))
+	}
+
+	report, err := cian.CheckSchema(ctx)
+	if err != nil {
+		fmt.Printf("listings: FAIL (%v)\n", err)
+		return errors.New("parser check failed")
+	}
+
+	if len(report.Errors) > 0 {
+		fmt.Printf("listings: FAIL (%d listings)\n", report.Listings)
+		for _, problem := range report.Errors {
+			fmt.Printf("  %s\n", problem)
+		}
+		failed = true
+	} else {
+		fmt.Printf("listings: ok (%d listings)\n", report.Listings)
+	}
+
+	missing := make([]string, 0, len(report.MissingFields))
+	for field := range report.MissingFields {
+		missing = append(missing, field)
+	}
+	sort.Strings(missing)
+	for _, field := range missing {
+		fmt.Printf("  warning: %d of %d listings have no %q\n", report.MissingFields[field], report.Listings, field)
+	}
+	for _, field := range report.UnknownFields {
+		fmt.Printf("  warning: unknown field %q is ignored\n", field)
+	}
+
+	if failed {
+		return errors.New("parser check failed")
+	}
+	return nil
+}
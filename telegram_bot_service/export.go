@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// writeExport writes records to stdout as JSON, or rows under header as CSV
+func writeExport(format string, header []string, rows [][]string, records interface{}) error {
+	switch format {
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return err
+		}
+		return w.Error()
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	default:
+		return fmt.Errorf("unknown format %q, use csv or json", format)
+	}
+}
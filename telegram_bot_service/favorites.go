@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"telegram_bot_service/internal/config"
+	"time"
+)
+
+const favoritesUsage = "usage: telegram_bot_service favorites export <user> [--format csv|json]"
+
+// exportedFavorite is a favorite without the preloaded user, which export doesn't load
+type exportedFavorite struct {
+	ListingID string    `json:"listing_id"`
+	Title     string    `json:"title"`
+	Price     string    `json:"price"`
+	URL       string    `json:"url"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// runFavorites implements the favorites subcommand
+func runFavorites(cfg *config.Config, args []string) error {
+	if len(args) < 2 || args[0] != "export" {
+		return errors.New(favoritesUsage)
+	}
+
+	userID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", args[1])
+	}
+
+	flags := flag.NewFlagSet("favorites export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "export format: csv or json")
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	app, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+
+	favorites, err := app.favorites.GetUserFavorites(context.Background(), userID)
+	if err != nil {
+		return err
+	}
+
+	records := make([]exportedFavorite, 0, len(favorites))
+	rows := make([][]string, 0, len(favorites))
+	for _, favorite := range favorites {
+		records = append(records, exportedFavorite{
+			ListingID: favorite.ListingID,
+			Title:     favorite.Title,
+			Price:     favorite.Price,
+			URL:       favorite.URL,
+			Note:      favorite.Note,
+			CreatedAt: favorite.CreatedAt,
+		})
+		rows = append(rows, []string{
+			favorite.ListingID, favorite.Title, favorite.Price, favorite.URL, favorite.Note, favorite.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	header := []string{"listing_id", "title", "price", "url", "note", "created_at"}
+	return writeExport(*format, header, rows, records)
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const defaultInviteUses = 1
+
+// checkAccess verifies that the sender may use the bot, redeeming a /start invite code if present
+func (b *Bot) checkAccess(ctx context.Context, message *tgbotapi.Message) bool {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	allowed, err := b.accessService.HasAccess(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to check user access")
+		b.sendMessage(ctx, chatID, "❌ Произошла ошибка. Попробуйте позже.")
+		return false
+	}
+	if allowed {
+		return true
+	}
+
+	code := strings.TrimSpace(message.CommandArguments())
+	if b.accessService.Mode() == services.AccessModeInvite && message.Command() == "start" && code != "" {
+		err := b.accessService.RedeemInviteCode(ctx, code, userID)
+		if err == nil {
+			logging.FromContext(ctx).WithField("user_id", userID).Info("Invite code redeemed")
+			return true
+		}
+
+		if errors.Is(err, services.ErrInvalidInviteCode) {
+			b.sendMessage(ctx, chatID, "❌ Код приглашения недействителен или уже использован.")
+		} else {
+			logging.FromContext(ctx).WithError(err).Error("Failed to redeem invite code")
+			b.sendMessage(ctx, chatID, "❌ Произошла ошибка. Попробуйте позже.")
+		}
+		return false
+	}
+
+	logging.FromContext(ctx).WithField("user_id", userID).Info("Access denied")
+	b.sendAccessDenied(ctx, chatID, userID)
+	return false
+}
+
+func (b *Bot) sendAccessDenied(ctx context.Context, chatID int64, userID int64) {
+	if b.accessService.Mode() == services.AccessModeInvite {
+		b.sendMessage(ctx, chatID, "🔒 Извините, бот работает по приглашениям. Попросите у администратора ссылку-приглашение.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, fmt.Sprintf("🔒 Извините, доступ к боту ограничен. Чтобы получить доступ, сообщите администратору ваш ID: `%d`", userID))
+}
+
+func (b *Bot) handleAdminInvite(ctx context.Context, chatID int64, userID int64, params []string) {
+	uses := defaultInviteUses
+	if len(params) > 0 {
+		parsed, err := strconv.Atoi(params[0])
+		if err != nil || parsed < 1 {
+			b.sendMessage(ctx, chatID, "Использование: /admin invite [количество использований]")
+			return
+		}
+		uses = parsed
+	}
+
+	invite, err := b.accessService.CreateInviteCode(ctx, userID, uses)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to create invite code")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при создании приглашения.")
+		return
+	}
+
+	link := fmt.Sprintf("https://t.me/%s?start=%s", b.api.Self.UserName, invite.Code)
+	b.sendMessage(ctx, chatID, fmt.Sprintf("🎟️ Приглашение на %d использований:\n`%s`", invite.MaxUses, link))
+}
+
+func (b *Bot) handleAdminInvites(ctx context.Context, chatID int64) {
+	invites, err := b.accessService.GetActiveInviteCodes(ctx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get invite codes")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении приглашений.")
+		return
+	}
+
+	if len(invites) == 0 {
+		b.sendMessage(ctx, chatID, "🎟️ Активных приглашений нет.")
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("🎟️ *Активные приглашения (%d):*\n\n", len(invites)))
+	for _, invite := range invites {
+		message.WriteString(fmt.Sprintf("`%s` - использовано %d/%d, создано %s\n",
+			invite.Code, invite.Uses, invite.MaxUses, invite.CreatedAt.Format("02.01.2006 15:04")))
+	}
+
+	b.sendMessage(ctx, chatID, message.String())
+}
+
+func (b *Bot) handleAdminDeleteInvite(ctx context.Context, chatID int64, params []string) {
+	if len(params) != 1 {
+		b.sendMessage(ctx, chatID, "Использование: /admin uninvite <код>")
+		return
+	}
+
+	if err := b.accessService.DeleteInviteCode(ctx, params[0]); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to delete invite code")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при удалении приглашения.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, "🗑️ Приглашение удалено.")
+}
+
+func (b *Bot) handleAdminApprove(ctx context.Context, chatID int64, userID int64, params []string) {
+	targetID, ok := b.parseAdminTargetID(ctx, chatID, params, "/admin approve <id>")
+	if !ok {
+		return
+	}
+
+	if err := b.accessService.GrantAccess(ctx, targetID, userID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to grant access")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при выдаче доступа.")
+		return
+	}
+
+	// Undoes the deactivation by revoke, so the user gets notifications again
+	if err := b.userService.ActivateUser(ctx, targetID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to activate user")
+	}
+
+	logging.FromContext(ctx).WithField("target_user_id", targetID).Info("Access granted by admin")
+	b.sendMessage(ctx, chatID, fmt.Sprintf("✅ Пользователю `%d` выдан доступ.", targetID))
+}
+
+func (b *Bot) handleAdminRevoke(ctx context.Context, chatID int64, params []string) {
+	targetID, ok := b.parseAdminTargetID(ctx, chatID, params, "/admin revoke <id>")
+	if !ok {
+		return
+	}
+
+	if err := b.accessService.RevokeAccess(ctx, targetID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to revoke access")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при отзыве доступа.")
+		return
+	}
+
+	if err := b.userService.DeactivateUser(ctx, targetID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to deactivate user")
+	}
+
+	logging.FromContext(ctx).WithField("target_user_id", targetID).Info("Access revoked by admin")
+	b.sendMessage(ctx, chatID, fmt.Sprintf("🚫 Доступ пользователя `%d` отозван.", targetID))
+}
+
+// parseAdminTargetID parses a single user ID argument, replying with usage on failure
+func (b *Bot) parseAdminTargetID(ctx context.Context, chatID int64, params []string, usage string) (int64, bool) {
+	if len(params) != 1 {
+		b.sendMessage(ctx, chatID, "Использование: "+usage)
+		return 0, false
+	}
+
+	targetID, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil {
+		b.sendMessage(ctx, chatID, "❌ Некорректный ID пользователя.")
+		return 0, false
+	}
+
+	return targetID, true
+}
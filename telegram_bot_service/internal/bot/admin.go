@@ -0,0 +1,265 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/database"
+	"telegram_bot_service/internal/logging"
+
+	"github.com/sirupsen/logrus"
+)
+
+const adminUsersListLimit = 50
+
+func (b *Bot) handleAdminCommand(ctx context.Context, chatID int64, userID int64, args string) {
+	if !b.userService.IsAdmin(userID) {
+		logging.FromContext(ctx).WithField("user_id", userID).Warn("Non-admin user tried to use admin command")
+		b.sendMessage(ctx, chatID, "⛔ Эта команда доступна только администраторам.")
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.handleAdminHelp(ctx, chatID)
+		return
+	}
+
+	subcommand := fields[0]
+	params := fields[1:]
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"user_id":    userID,
+		"subcommand": subcommand,
+	}).Info("Received admin command")
+
+	switch subcommand {
+	case "stats":
+		b.handleAdminStats(ctx, chatID)
+	case "settings":
+		b.handleSettingsCommand(ctx, chatID)
+	case "set":
+		b.handleAdminSetSetting(ctx, chatID, params)
+	case "refresh":
+		b.handleAdminRefresh(ctx, chatID)
+	case "backup":
+		b.handleAdminBackup(ctx, chatID)
+	case "broadcast":
+		b.handleBroadcastCommand(ctx, chatID, userID, strings.TrimPrefix(strings.TrimSpace(args), subcommand))
+	case "users":
+		b.handleAdminUsers(ctx, chatID)
+	case "deactivate":
+		b.handleAdminDeactivate(ctx, chatID, params)
+	case "activate":
+		b.handleAdminActivate(ctx, chatID, params)
+	case "invite":
+		b.handleAdminInvite(ctx, chatID, userID, params)
+	case "invites":
+		b.handleAdminInvites(ctx, chatID)
+	case "uninvite":
+		b.handleAdminDeleteInvite(ctx, chatID, params)
+	case "approve":
+		b.handleAdminApprove(ctx, chatID, userID, params)
+	case "revoke":
+		b.handleAdminRevoke(ctx, chatID, params)
+	default:
+		b.handleAdminHelp(ctx, chatID)
+	}
+}
+
+func (b *Bot) handleAdminHelp(ctx context.Context, chatID int64) {
+	helpText := `🛠️ Команды администратора:
+
+/admin stats - Статистика бота
+/admin settings - Показать настройки парсера
+/admin set <ключ> <значение> - Изменить настройку парсера
+/admin refresh - Принудительно обновить объявления
+/admin backup - Создать резервную копию базы данных
+/admin broadcast <текст> - Разослать сообщение всем активным пользователям (то же, что /broadcast)
+/admin users - Список пользователей
+/admin deactivate <id> - Деактивировать пользователя
+/admin activate <id> - Снова активировать пользователя
+/admin invite [N] - Создать приглашение на N использований
+/admin invites - Активные приглашения
+/admin uninvite <код> - Удалить приглашение
+/admin approve <id> - Выдать доступ пользователю
+/admin revoke <id> - Отозвать доступ пользователя`
+
+	b.sendMessage(ctx, chatID, helpText)
+}
+
+func (b *Bot) handleAdminStats(ctx context.Context, chatID int64) {
+	totalUsers, activeUsers, err := b.userService.CountUsers(ctx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to count users")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении статистики.")
+		return
+	}
+
+	subscriptions, err := b.subscriptionService.CountActiveSubscriptions(ctx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to count subscriptions")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении статистики.")
+		return
+	}
+
+	favorites, err := b.favoriteService.CountFavorites(ctx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to count favorites")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении статистики.")
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString("📊 *Статистика бота:*\n\n")
+	message.WriteString(fmt.Sprintf("👥 Пользователей: %d (активных: %d)\n", totalUsers, activeUsers))
+	message.WriteString(fmt.Sprintf("🔔 Активных подписок: %d\n", subscriptions))
+	message.WriteString(fmt.Sprintf("⭐ Избранных объявлений: %d\n", favorites))
+
+	b.sendMessage(ctx, chatID, message.String())
+}
+
+func (b *Bot) handleAdminSetSetting(ctx context.Context, chatID int64, params []string) {
+	if len(params) < 2 {
+		b.sendMessage(ctx, chatID, "Использование: /admin set <ключ> <значение>")
+		return
+	}
+
+	key := params[0]
+	value := parseSettingValue(strings.Join(params[1:], " "))
+
+	if err := b.cianService.UpdateSettings(ctx, map[string]interface{}{key: value}); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to update settings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при обновлении настроек.")
+		return
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"key":   key,
+		"value": value,
+	}).Info("Parser settings updated by admin")
+
+	b.sendMessage(ctx, chatID, fmt.Sprintf("✅ Настройка *%s* обновлена: %v", escapeMarkdown(key), value))
+}
+
+func (b *Bot) handleAdminRefresh(ctx context.Context, chatID int64) {
+	b.sendMessage(ctx, chatID, "🔄 Обновление объявлений запущено...")
+
+	listings, err := b.cianService.GetListings(ctx, true)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to force refresh listings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при обновлении объявлений.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, fmt.Sprintf("✅ Объявления обновлены, получено: %d", len(listings)))
+}
+
+func (b *Bot) handleAdminBackup(ctx context.Context, chatID int64) {
+	if b.options.Backups == nil {
+		b.sendMessage(ctx, chatID, "❌ Резервное копирование не настроено.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, "💾 Создаю резервную копию базы данных...")
+
+	result, err := b.options.Backups.Run(ctx)
+	if errors.Is(err, database.ErrBackupUnsupported) {
+		b.sendMessage(ctx, chatID, "❌ Резервное копирование доступно только для SQLite.")
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to back up database")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при создании резервной копии.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, fmt.Sprintf("✅ Резервная копия создана: `%s` (%.1f КБ за %d мс)",
+		filepath.Base(result.Path), float64(result.Size)/1024, result.Duration.Milliseconds()))
+}
+
+func (b *Bot) handleAdminUsers(ctx context.Context, chatID int64) {
+	users, err := b.userService.ListUsers(ctx, adminUsersListLimit)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to list users")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении списка пользователей.")
+		return
+	}
+
+	if len(users) == 0 {
+		b.sendMessage(ctx, chatID, "👥 Пользователей пока нет.")
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("👥 *Пользователи (последние %d):*\n\n", len(users)))
+
+	for _, user := range users {
+		status := "✅"
+		switch {
+		case !user.IsActive:
+			status = "🚫"
+		case user.BlockedAt != nil:
+			status = "🔇"
+		}
+		message.WriteString(fmt.Sprintf("%s `%d`", status, user.ID))
+		if user.Username != "" {
+			message.WriteString(fmt.Sprintf(" @%s", escapeMarkdown(user.Username)))
+		}
+		if user.IsAdmin {
+			message.WriteString(" 🛠️")
+		}
+		message.WriteString("\n")
+	}
+
+	b.sendMessage(ctx, chatID, message.String())
+}
+
+func (b *Bot) handleAdminDeactivate(ctx context.Context, chatID int64, params []string) {
+	targetID, ok := b.parseAdminTargetID(ctx, chatID, params, "/admin deactivate <id>")
+	if !ok {
+		return
+	}
+
+	if err := b.userService.DeactivateUser(ctx, targetID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to deactivate user")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при деактивации пользователя.")
+		return
+	}
+
+	logging.FromContext(ctx).WithField("target_user_id", targetID).Info("User deactivated by admin")
+	b.sendMessage(ctx, chatID, fmt.Sprintf("🚫 Пользователь `%d` деактивирован.", targetID))
+}
+
+func (b *Bot) handleAdminActivate(ctx context.Context, chatID int64, params []string) {
+	targetID, ok := b.parseAdminTargetID(ctx, chatID, params, "/admin activate <id>")
+	if !ok {
+		return
+	}
+
+	if err := b.userService.ActivateUser(ctx, targetID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to activate user")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при активации пользователя.")
+		return
+	}
+
+	logging.FromContext(ctx).WithField("target_user_id", targetID).Info("User activated by admin")
+	b.sendMessage(ctx, chatID, fmt.Sprintf("✅ Пользователь `%d` снова активен.", targetID))
+}
+
+// parseSettingValue converts a raw admin input into the JSON type the parser expects
+func parseSettingValue(raw string) interface{} {
+	if value, err := strconv.Atoi(raw); err == nil {
+		return value
+	}
+	if value, err := strconv.ParseFloat(raw, 64); err == nil {
+		return value
+	}
+	if value, err := strconv.ParseBool(raw); err == nil {
+		return value
+	}
+	return raw
+}
@@ -1,21 +1,111 @@
 package bot
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"telegram_bot_service/internal/database"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/metrics"
 	"telegram_bot_service/internal/services"
+	"telegram_bot_service/internal/tracing"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// Options holds tunables for update processing
+type Options struct {
+	RateLimits RateLimits
+	Workers    int
+	QueueSize  int
+
+	// Mode is ModePolling or ModeWebhook
+	Mode          string
+	WebhookURL    string
+	WebhookPath   string
+	WebhookSecret string
+
+	// Backups serves /admin backup; nil disables the command
+	Backups *database.BackupScheduler
+
+	// History enables notifications to subscribers about new listings every CheckInterval; nil disables them
+	History       *services.ListingHistoryService
+	CheckInterval time.Duration
+
+	// Ranking orders listings by each user's score; nil keeps the parser's order
+	Ranking *services.RankingService
+	// Market compares prices per square meter in listing cards with similar listings; nil shows them alone
+	Market *services.MarketService
+
+	// Sender replaces the Bot API for outgoing requests when set, e.g. to record them in tests
+	Sender Sender
+	// SendRate caps the messages sent per second across all chats; 0 uses the default
+	SendRate int
+
+	// APIEndpoint overrides the Telegram Bot API endpoint format, e.g. to use a local Bot API
+	// server or the fake one from internal/telegramtest
+	APIEndpoint string
+
+	Messages Messages
+}
+
+// Messages overrides texts of the bot; empty fields keep the built-in texts
+type Messages struct {
+	Welcome string
+	Help    string
+}
+
+// Sender delivers requests to Telegram; *tgbotapi.BotAPI implements it
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+}
+
 type Bot struct {
-	api             *tgbotapi.BotAPI
-	cianService     *services.CianService
-	userService     *services.UserService
-	favoriteService *services.FavoriteService
+	api                 *tgbotapi.BotAPI
+	sender              Sender
+	cianService         *services.CianService
+	userService         *services.UserService
+	favoriteService     *services.FavoriteService
+	subscriptionService *services.SubscriptionService
+	accessService       *services.AccessService
+	limiters            *rateLimiters
+	dispatcher          *dispatcher
+	outbox              *outbox
+	options             Options
+	stop                chan struct{}
+	stopOnce            sync.Once
+	// background tracks broadcasts and the notifier, which Start waits for after Stop
+	background sync.WaitGroup
+
+	broadcastMu     sync.Mutex
+	broadcastDrafts map[int64]*broadcastDraft
+
+	// searches are the last /search query of each user, for paging through the results;
+	// searchDrafts are saved searches being created
+	searchMu     sync.Mutex
+	searches     map[int64]*services.SearchQuery
+	searchDrafts map[int64]*searchDraft
+
+	messagesMu sync.RWMutex
+	messages   Messages
+
+	// notifyMu guards options.CheckInterval; notifyReset wakes the notifier when it changes
+	notifyMu    sync.Mutex
+	notifyReset chan struct{}
 }
 
-func New(token string, cianService *services.CianService, userService *services.UserService, favoriteService *services.FavoriteService) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+func New(token string, cianService *services.CianService, userService *services.UserService, favoriteService *services.FavoriteService, subscriptionService *services.SubscriptionService, accessService *services.AccessService, options Options) (*Bot, error) {
+	endpoint := options.APIEndpoint
+	if endpoint == "" {
+		endpoint = tgbotapi.APIEndpoint
+	}
+
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -23,82 +113,284 @@ func New(token string, cianService *services.CianService, userService *services.
 	api.Debug = false
 	logrus.WithField("username", api.Self.UserName).Info("Authorized on account")
 
-	return &Bot{
-		api:             api,
-		cianService:     cianService,
-		userService:     userService,
-		favoriteService: favoriteService,
-	}, nil
+	b := NewWithAPI(api, cianService, userService, favoriteService, subscriptionService, accessService, options)
+	b.registerMetrics()
+
+	return b, nil
+}
+
+// NewWithAPI creates a bot around an existing API client without contacting Telegram.
+// Unlike New it doesn't register metrics, so it can be called repeatedly, e.g. in tests.
+func NewWithAPI(api *tgbotapi.BotAPI, cianService *services.CianService, userService *services.UserService, favoriteService *services.FavoriteService, subscriptionService *services.SubscriptionService, accessService *services.AccessService, options Options) *Bot {
+	var sender Sender = api
+	if options.Sender != nil {
+		sender = options.Sender
+	}
+
+	b := &Bot{
+		api:                 api,
+		sender:              sender,
+		cianService:         cianService,
+		userService:         userService,
+		favoriteService:     favoriteService,
+		subscriptionService: subscriptionService,
+		accessService:       accessService,
+		limiters:            newRateLimiters(options.RateLimits),
+		outbox:              newOutbox(options.SendRate),
+		broadcastDrafts:     make(map[int64]*broadcastDraft),
+		searches:            make(map[int64]*services.SearchQuery),
+		searchDrafts:        make(map[int64]*searchDraft),
+		messages:            options.Messages,
+		options:             options,
+		notifyReset:         make(chan struct{}, 1),
+		stop:                make(chan struct{}),
+	}
+	b.dispatcher = newDispatcher(options.Workers, options.QueueSize, b.handleUpdate)
+
+	return b
 }
 
 func (b *Bot) Start() error {
+	b.dispatcher.Start()
+	defer b.background.Wait()
+	defer b.dispatcher.Stop()
+
+	b.startNotifier()
+
+	if b.options.Mode == ModeWebhook {
+		return b.runWebhook()
+	}
+	return b.runPolling()
+}
+
+// Stop stops receiving updates and interrupts running broadcasts; Start returns once queued
+// updates are handled
+func (b *Bot) Stop() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+		b.api.StopReceivingUpdates()
+	})
+}
+
+// goBackground runs f in a goroutine with a context that is cancelled when the bot stops;
+// Start waits for it before returning
+func (b *Bot) goBackground(ctx context.Context, f func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	b.background.Add(1)
+	go func() {
+		defer b.background.Done()
+		defer cancel()
+		f(ctx)
+	}()
+	go func() {
+		select {
+		case <-b.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+}
+
+func (b *Bot) runPolling() error {
+	// getUpdates is rejected by Telegram while a webhook is set
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		logrus.WithError(err).Warn("Failed to delete webhook")
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
 	updates := b.api.GetUpdatesChan(u)
 
+	logrus.Info("Receiving updates via long polling")
 	for update := range updates {
-		if update.Message != nil {
-			go b.handleMessage(update.Message)
-		} else if update.CallbackQuery != nil {
-			go b.handleCallbackQuery(update.CallbackQuery)
-		}
+		b.dispatcher.Dispatch(update)
 	}
 
 	return nil
 }
 
-func (b *Bot) handleMessage(message *tgbotapi.Message) {
+// QueueDepth returns the number of updates waiting for a worker
+func (b *Bot) QueueDepth() int {
+	return b.dispatcher.QueueDepth()
+}
+
+// HandleUpdates processes updates through a worker pool like the one Start runs and returns
+// once all of them are handled, so tests exercise the same sharding as production
+func (b *Bot) HandleUpdates(updates ...tgbotapi.Update) {
+	d := newDispatcher(b.options.Workers, b.options.QueueSize, b.handleUpdate)
+	d.Start()
+	for _, update := range updates {
+		d.Dispatch(update)
+	}
+	d.Stop()
+}
+
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	start := time.Now()
+
+	fields := logrus.Fields{"update_id": update.UpdateID}
+	if user := update.SentFrom(); user != nil {
+		fields["user_id"] = user.ID
+	}
+	if chat := update.FromChat(); chat != nil {
+		fields["chat_id"] = chat.ID
+	}
+
+	var updateType string
+	resultFields := logrus.Fields{}
+	attrs := []attribute.KeyValue{attribute.Int("telegram.update_id", update.UpdateID)}
+	if update.Message != nil {
+		updateType = "message"
+		if update.Message.IsCommand() {
+			resultFields["command"] = update.Message.Command()
+			attrs = append(attrs, attribute.String("telegram.command", update.Message.Command()))
+		}
+	} else if update.CallbackQuery != nil {
+		updateType = "callback"
+		action := callbackMetricLabel(strings.Split(update.CallbackQuery.Data, ":")[0])
+		resultFields["action"] = action
+		attrs = append(attrs, attribute.String("telegram.action", action))
+	} else if update.InlineQuery != nil {
+		updateType = "inline_query"
+	} else {
+		return
+	}
+	attrs = append(attrs, attribute.String("telegram.update_type", updateType))
+
+	ctx, span := tracing.Start(context.Background(), "telegram.update", attrs...)
+	defer span.End()
+
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		fields["trace_id"] = traceID
+	}
+	ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	ctx = logging.WithFields(ctx, fields)
+
+	switch {
+	case update.Message != nil:
+		b.handleMessage(ctx, update.Message)
+	case update.CallbackQuery != nil:
+		b.handleCallbackQuery(ctx, update.CallbackQuery)
+	default:
+		b.handleInlineQuery(ctx, update.InlineQuery)
+	}
+
+	latency := time.Since(start)
+	metrics.HandlerDuration.WithLabelValues(updateType).Observe(latency.Seconds())
+
+	resultFields["update_type"] = updateType
+	resultFields["latency_ms"] = latency.Milliseconds()
+	logging.FromContext(ctx).WithFields(resultFields).Info("Update handled")
+}
+
+func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
+	if !b.allowMessage(ctx, message.Chat.ID, message.From.ID) {
+		return
+	}
+
+	if !b.checkAccess(ctx, message) {
+		return
+	}
+
 	// Create or update user
 	_, err := b.userService.CreateOrUpdateUser(
+		ctx,
 		message.From.ID,
 		message.From.UserName,
 		message.From.FirstName,
 		message.From.LastName,
 	)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create/update user")
+		logging.FromContext(ctx).WithError(err).Error("Failed to create/update user")
 		return
 	}
 
 	if message.IsCommand() {
-		b.handleCommand(message)
+		b.handleCommand(ctx, message)
 	} else {
-		b.handleTextMessage(message)
+		b.handleTextMessage(ctx, message)
 	}
 }
 
-func (b *Bot) handleCommand(message *tgbotapi.Message) {
+func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message) {
 	chatID := message.Chat.ID
 	command := message.Command()
 
-	logrus.WithFields(logrus.Fields{
-		"user_id":  message.From.ID,
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"username": message.From.UserName,
 		"command":  command,
 	}).Info("Received command")
 
+	metrics.CommandsTotal.WithLabelValues(commandMetricLabel(command)).Inc()
+
 	switch command {
 	case "start":
-		b.handleStartCommand(chatID)
+		// The inline mode links here when a query can't be understood
+		if message.CommandArguments() == searchHelpParameter {
+			b.handleSearchCommand(ctx, chatID, message.From.ID, "")
+			return
+		}
+		b.handleStartCommand(ctx, chatID)
 	case "help":
-		b.handleHelpCommand(chatID)
+		b.handleHelpCommand(ctx, chatID)
 	case "listings":
-		b.handleListingsCommand(chatID)
+		b.handleListingsCommand(ctx, chatID, message.From.ID)
 	case "favorites":
-		b.handleFavoritesCommand(chatID, message.From.ID)
+		b.handleFavoritesCommand(ctx, chatID, message.From.ID)
 	case "settings":
-		b.handleSettingsCommand(chatID)
+		b.handleSettingsCommand(ctx, chatID)
 	case "subscribe":
-		b.handleSubscribeCommand(chatID, message.From.ID)
+		b.handleSubscribeCommand(ctx, chatID, message.From.ID)
 	case "unsubscribe":
-		b.handleUnsubscribeCommand(chatID, message.From.ID)
+		b.handleUnsubscribeCommand(ctx, chatID, message.From.ID)
+	case "admin":
+		b.handleAdminCommand(ctx, chatID, message.From.ID, message.CommandArguments())
+	case "broadcast":
+		b.handleBroadcastCommand(ctx, chatID, message.From.ID, message.CommandArguments())
+	case "cancel":
+		b.handleCancelCommand(ctx, chatID, message.From.ID)
+	case "stats":
+		b.handleStatsCommand(ctx, chatID)
+	case "ranking":
+		b.handleRankingCommand(ctx, chatID, message.From.ID, message.CommandArguments())
+	case "search":
+		b.handleSearchCommand(ctx, chatID, message.From.ID, message.CommandArguments())
+	case "searches":
+		b.handleSavedSearchesCommand(ctx, chatID, message.From.ID, message.CommandArguments())
 	default:
-		b.sendMessage(chatID, "Неизвестная команда. Используйте /help для списка доступных команд.")
+		b.sendMessage(ctx, chatID, "Неизвестная команда. Используйте /help для списка доступных команд.")
 	}
 }
 
-func (b *Bot) handleStartCommand(chatID int64) {
+// knownCommands bounds the label values of the commands metric
+var knownCommands = map[string]bool{
+	"start": true, "help": true, "listings": true, "favorites": true, "settings": true,
+	"subscribe": true, "unsubscribe": true, "admin": true, "broadcast": true, "cancel": true,
+	"ranking": true, "stats": true, "search": true, "searches": true,
+}
+
+func commandMetricLabel(command string) string {
+	if knownCommands[command] {
+		return command
+	}
+	return "unknown"
+}
+
+// SetMessages replaces the message texts of a running bot
+func (b *Bot) SetMessages(messages Messages) {
+	b.messagesMu.Lock()
+	defer b.messagesMu.Unlock()
+	b.messages = messages
+}
+
+func (b *Bot) currentMessages() Messages {
+	b.messagesMu.RLock()
+	defer b.messagesMu.RUnlock()
+	return b.messages
+}
+
+func (b *Bot) handleStartCommand(ctx context.Context, chatID int64) {
 	welcomeText := `🏠 Добро пожаловать в бот уведомлений о недвижимости ЦИАН!
 
 Этот бот поможет вам:
@@ -108,31 +400,82 @@ func (b *Bot) handleStartCommand(chatID int64) {
 • ⚙️ Настраивать параметры поиска
 
 Используйте /help для получения списка команд.`
+	if custom := b.currentMessages().Welcome; custom != "" {
+		welcomeText = custom
+	}
 
-	b.sendMessage(chatID, welcomeText)
+	b.sendMessage(ctx, chatID, welcomeText)
 }
 
-func (b *Bot) handleHelpCommand(chatID int64) {
+func (b *Bot) handleHelpCommand(ctx context.Context, chatID int64) {
 	helpText := `📖 Доступные команды:
 
 /start - Начать работу с ботом
 /help - Показать эту справку
 /listings - Показать текущие объявления
+/search - Найти объявления, например: /search 2к до 60000 метро Сокольники
 /favorites - Показать избранные объявления
 /settings - Показать и настроить параметры поиска
 /subscribe - Подписаться на уведомления
 /unsubscribe - Отписаться от уведомлений
+/searches - Сохранённые поиски с отдельными уведомлениями
+/ranking - Настроить порядок объявлений
+/stats - Статистика рынка с графиками
 
 💡 Tip: Вы можете добавлять объявления в избранное прямо из списка!`
+	if custom := b.currentMessages().Help; custom != "" {
+		helpText = custom
+	}
 
-	b.sendMessage(chatID, helpText)
+	b.sendMessage(ctx, chatID, helpText)
+}
+
+func (b *Bot) sendMessage(ctx context.Context, chatID int64, text string) {
+	if err := b.trySendMessage(ctx, chatID, text); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to send message")
+	}
 }
 
-func (b *Bot) sendMessage(chatID int64, text string) {
+// trySendMessage sends a message and returns the delivery error to the caller
+func (b *Bot) trySendMessage(ctx context.Context, chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = tgbotapi.ModeMarkdown
 
-	if _, err := b.api.Send(msg); err != nil {
-		logrus.WithError(err).Error("Failed to send message")
+	_, err := b.send(ctx, msg)
+	return err
+}
+
+// send delivers c to Telegram inside a span so slow API calls show up in traces. It waits for
+// its turn in the outbox, so it may block while a broadcast is running.
+func (b *Bot) send(ctx context.Context, c tgbotapi.Chattable) (message tgbotapi.Message, err error) {
+	_, span := tracing.Start(ctx, "telegram.send", attribute.String("telegram.request", fmt.Sprintf("%T", c)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := b.outbox.wait(ctx); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	return b.sender.Send(c)
+}
+
+// request is like send for API methods that don't return a message
+func (b *Bot) request(ctx context.Context, c tgbotapi.Chattable) (response *tgbotapi.APIResponse, err error) {
+	_, span := tracing.Start(ctx, "telegram.request", attribute.String("telegram.request", fmt.Sprintf("%T", c)))
+	defer func() { tracing.End(span, err) }()
+
+	if postsToChat(c) {
+		if err := b.outbox.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return b.sender.Request(c)
+}
+
+// postsToChat reports whether a request posts to a chat and counts against Telegram's
+// message limit; answers to callbacks and inline queries don't
+func postsToChat(c tgbotapi.Chattable) bool {
+	switch c.(type) {
+	case tgbotapi.CallbackConfig, tgbotapi.InlineConfig:
+		return false
 	}
+	return true
 }
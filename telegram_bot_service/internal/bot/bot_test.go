@@ -0,0 +1,105 @@
+package bot_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"telegram_bot_service/internal/bot"
+	"telegram_bot_service/internal/bot/bottest"
+	"telegram_bot_service/internal/parsertest"
+	"telegram_bot_service/internal/repository"
+	"telegram_bot_service/internal/services"
+)
+
+func TestDeactivatedUserStaysDeactivated(t *testing.T) {
+	ctx := context.Background()
+	h := bottest.New(bottest.Config{})
+
+	h.Message(42, "/start")
+	h.Message(42, "/subscribe")
+	if err := h.Users.SetUserActive(ctx, 42, false); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Message(42, "/help")
+
+	user, err := h.Users.GetUser(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsActive {
+		t.Error("a message reactivated a deactivated user")
+	}
+
+	subscriptions, err := h.Subscriptions.ListActiveSubscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 0 {
+		t.Errorf("active subscriptions of a deactivated user: %+v", subscriptions)
+	}
+}
+
+func TestAdminActivatesDeactivatedUser(t *testing.T) {
+	ctx := context.Background()
+	h := bottest.New(bottest.Config{AdminIDs: []int64{1}})
+
+	h.Message(42, "/subscribe")
+	h.Message(1, "/admin deactivate 42")
+	h.Message(42, "/start")
+	if user, err := h.Users.GetUser(ctx, 42); err != nil || user.IsActive {
+		t.Fatalf("after /admin deactivate and /start: %+v, %v", user, err)
+	}
+
+	if reply := lastMessage(t, h.Message(1, "/admin activate 42")); !strings.Contains(reply, "снова активен") {
+		t.Errorf("/admin activate reply = %q", reply)
+	}
+	if user, err := h.Users.GetUser(ctx, 42); err != nil || !user.IsActive {
+		t.Errorf("after /admin activate: %+v, %v", user, err)
+	}
+	subscriptions, err := h.Subscriptions.ListActiveSubscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 1 {
+		t.Errorf("active subscriptions after /admin activate: %+v", subscriptions)
+	}
+}
+
+func TestRevokedThenApprovedUserIsNotified(t *testing.T) {
+	ctx := context.Background()
+	parser := parsertest.NewServer(testListings[:1])
+	defer parser.Close()
+
+	history := services.NewListingHistoryService(services.NewCianService(parser.URL), repository.NewMemoryListingRepository(), nil)
+	h := bottest.New(bottest.Config{
+		CianAPIURL: parser.URL,
+		AdminIDs:   []int64{1},
+		AccessMode: services.AccessModeAllowlist,
+		Options:    bot.Options{History: history},
+	})
+	// The first check only records the listings on the market
+	if _, err := h.Bot.NotifyOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Message(1, "/admin approve 42")
+	h.Message(42, "/subscribe")
+	h.Message(1, "/admin revoke 42")
+	h.Message(1, "/admin approve 42")
+
+	parser.SetListings(testListings)
+	before := h.Sender.Len()
+	if _, err := h.Bot.NotifyOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var notified bool
+	for _, sent := range h.Sender.Sent()[before:] {
+		notified = notified || (sent.ChatID == 42 && strings.Contains(sent.Text, "Новое объявление"))
+	}
+	if !notified {
+		t.Errorf("no notification after revoke and approve: %+v", h.Sender.Sent()[before:])
+	}
+}
@@ -0,0 +1,264 @@
+// Package bottest runs the bot's update handlers against in-memory repositories and a
+// recording Telegram sender, so tests can script a conversation and inspect the replies.
+package bottest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"telegram_bot_service/internal/bot"
+	"telegram_bot_service/internal/repository"
+	"telegram_bot_service/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Sent is a request the bot made to Telegram
+type Sent struct {
+	// Method is the Bot API method, e.g. "sendMessage" or "answerCallbackQuery"
+	Method    string
+	ChatID    int64
+	MessageID int
+	Text      string
+	Markup    *tgbotapi.InlineKeyboardMarkup
+	Request   tgbotapi.Chattable
+}
+
+// Buttons returns the callback data of all inline keyboard buttons
+func (s Sent) Buttons() []string {
+	if s.Markup == nil {
+		return nil
+	}
+
+	var data []string
+	for _, row := range s.Markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data = append(data, *button.CallbackData)
+			}
+		}
+	}
+	return data
+}
+
+// RecordingSender implements bot.Sender by recording requests instead of sending them
+type RecordingSender struct {
+	mu            sync.Mutex
+	sent          []Sent
+	nextMessageID int
+}
+
+func (r *RecordingSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	sent := r.record(c)
+	return tgbotapi.Message{
+		MessageID: sent.MessageID,
+		Chat:      &tgbotapi.Chat{ID: sent.ChatID},
+		Text:      sent.Text,
+	}, nil
+}
+
+func (r *RecordingSender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	r.record(c)
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+// Sent returns all recorded requests in order
+func (r *RecordingSender) Sent() []Sent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Sent(nil), r.sent...)
+}
+
+// Len returns the number of recorded requests
+func (r *RecordingSender) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.sent)
+}
+
+func (r *RecordingSender) record(c tgbotapi.Chattable) Sent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sent := Sent{Request: c}
+	switch req := c.(type) {
+	case tgbotapi.MessageConfig:
+		r.nextMessageID++
+		sent.Method = "sendMessage"
+		sent.ChatID = req.ChatID
+		sent.MessageID = r.nextMessageID
+		sent.Text = req.Text
+		if markup, ok := req.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); ok {
+			sent.Markup = &markup
+		}
+	case tgbotapi.EditMessageTextConfig:
+		sent.Method = "editMessageText"
+		sent.ChatID = req.ChatID
+		sent.MessageID = req.MessageID
+		sent.Text = req.Text
+		sent.Markup = req.ReplyMarkup
+	case tgbotapi.CallbackConfig:
+		sent.Method = "answerCallbackQuery"
+		sent.Text = req.Text
+	case tgbotapi.PhotoConfig:
+		r.nextMessageID++
+		sent.Method = "sendPhoto"
+		sent.ChatID = req.ChatID
+		sent.MessageID = r.nextMessageID
+		sent.Text = req.Caption
+	case tgbotapi.InlineConfig:
+		sent.Method = "answerInlineQuery"
+		sent.Text = req.SwitchPMText
+	case tgbotapi.MediaGroupConfig:
+		r.nextMessageID++
+		sent.Method = "sendMediaGroup"
+		sent.ChatID = req.ChatID
+		sent.MessageID = r.nextMessageID
+	default:
+		sent.Method = fmt.Sprintf("%T", c)
+	}
+
+	r.sent = append(r.sent, sent)
+	return sent
+}
+
+// Config configures a Harness
+type Config struct {
+	// CianAPIURL points at the parser, typically an httptest.Server serving fixture listings
+	CianAPIURL string
+	AdminIDs   []int64
+	AccessMode string
+	AllowedIDs []int64
+	Options    bot.Options
+}
+
+// Harness is a bot wired to in-memory repositories and a RecordingSender
+type Harness struct {
+	Bot    *bot.Bot
+	Sender *RecordingSender
+
+	Users         *repository.MemoryUserRepository
+	Favorites     *repository.MemoryFavoriteRepository
+	Subscriptions *repository.MemorySubscriptionRepository
+	Access        *repository.MemoryAccessRepository
+	Listings      *repository.MemoryListingRepository
+
+	mu           sync.Mutex
+	nextUpdateID int
+}
+
+// testSendRate keeps the outbox from slowing tests down
+const testSendRate = 10000
+
+// New creates a harness; rate limits are off unless cfg.Options sets them
+func New(cfg Config) *Harness {
+	if cfg.AccessMode == "" {
+		cfg.AccessMode = services.AccessModeOpen
+	}
+
+	users := repository.NewMemoryUserRepository()
+	h := &Harness{
+		Sender:        &RecordingSender{},
+		Users:         users,
+		Favorites:     repository.NewMemoryFavoriteRepository(),
+		Subscriptions: repository.NewMemorySubscriptionRepository(users),
+		Access:        repository.NewMemoryAccessRepository(),
+		Listings:      repository.NewMemoryListingRepository(),
+	}
+
+	options := cfg.Options
+	options.Sender = h.Sender
+	if options.SendRate == 0 {
+		options.SendRate = testSendRate
+	}
+	if options.Market == nil {
+		options.Market = services.NewMarketService(h.Listings)
+	}
+	if options.Ranking == nil {
+		options.Ranking = services.NewRankingService(h.Users, h.Listings, options.Market)
+	}
+
+	api := &tgbotapi.BotAPI{Self: tgbotapi.User{ID: 1, IsBot: true, UserName: "test_bot"}}
+	h.Bot = bot.NewWithAPI(
+		api,
+		services.NewCianService(cfg.CianAPIURL),
+		services.NewUserService(h.Users, cfg.AdminIDs),
+		services.NewFavoriteService(h.Favorites),
+		services.NewSubscriptionService(h.Subscriptions),
+		services.NewAccessService(h.Access, cfg.AccessMode, cfg.AllowedIDs, cfg.AdminIDs),
+		options,
+	)
+	return h
+}
+
+// Message delivers a private chat message from userID and returns the requests the bot made
+// while handling it. Text starting with "/" is sent as a command.
+func (h *Harness) Message(userID int64, text string) []Sent {
+	message := &tgbotapi.Message{
+		MessageID: h.nextID(),
+		From:      &tgbotapi.User{ID: userID, UserName: fmt.Sprintf("user%d", userID), FirstName: "Test"},
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command := strings.SplitN(text, " ", 2)[0]
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+
+	return h.handle(tgbotapi.Update{UpdateID: h.nextID(), Message: message})
+}
+
+// Press presses an inline button with the given callback data in a private chat
+func (h *Harness) Press(userID int64, data string) []Sent {
+	query := &tgbotapi.CallbackQuery{
+		ID:   fmt.Sprintf("callback-%d", h.nextID()),
+		From: &tgbotapi.User{ID: userID, UserName: fmt.Sprintf("user%d", userID), FirstName: "Test"},
+		Message: &tgbotapi.Message{
+			MessageID: 1,
+			Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		},
+		Data: data,
+	}
+
+	return h.handle(tgbotapi.Update{UpdateID: h.nextID(), CallbackQuery: query})
+}
+
+// Inline sends an inline query from userID, as typed after the bot's username in any chat;
+// offset is empty for the first page
+func (h *Harness) Inline(userID int64, query, offset string) []Sent {
+	inline := &tgbotapi.InlineQuery{
+		ID:     fmt.Sprintf("inline-%d", h.nextID()),
+		From:   &tgbotapi.User{ID: userID, UserName: fmt.Sprintf("user%d", userID), FirstName: "Test"},
+		Query:  query,
+		Offset: offset,
+	}
+
+	return h.handle(tgbotapi.Update{UpdateID: h.nextID(), InlineQuery: inline})
+}
+
+// Messages returns the texts of messages sent or edited in sent, skipping callback answers
+func Messages(sent []Sent) []string {
+	var texts []string
+	for _, s := range sent {
+		if s.Method == "sendMessage" || s.Method == "editMessageText" {
+			texts = append(texts, s.Text)
+		}
+	}
+	return texts
+}
+
+func (h *Harness) handle(update tgbotapi.Update) []Sent {
+	before := h.Sender.Len()
+	h.Bot.HandleUpdates(update)
+	return h.Sender.Sent()[before:]
+}
+
+func (h *Harness) nextID() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextUpdateID++
+	return h.nextUpdateID
+}
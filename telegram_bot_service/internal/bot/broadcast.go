@@ -0,0 +1,370 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/metrics"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	broadcastProgressInterval = 2 * time.Second
+	broadcastMaxRetries       = 3
+)
+
+// broadcastDraft is a broadcast message awaiting admin confirmation
+type broadcastDraft struct {
+	ID     string
+	ChatID int64
+	Text   string
+}
+
+// BroadcastReport accumulates delivery results of a broadcast
+type BroadcastReport struct {
+	Total    int
+	Sent     int
+	Failed   int
+	Failures map[string]int
+}
+
+func (b *Bot) handleBroadcastCommand(ctx context.Context, chatID int64, userID int64, text string) {
+	if !b.userService.IsAdmin(userID) {
+		logging.FromContext(ctx).WithField("user_id", userID).Warn("Non-admin user tried to use broadcast command")
+		b.sendMessage(ctx, chatID, "⛔ Эта команда доступна только администраторам.")
+		return
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		b.broadcastMu.Lock()
+		b.broadcastDrafts[userID] = &broadcastDraft{ChatID: chatID}
+		b.broadcastMu.Unlock()
+
+		b.sendMessage(ctx, chatID, "📣 Отправьте текст рассылки следующим сообщением. Для отмены используйте /cancel.")
+		return
+	}
+
+	b.previewBroadcast(ctx, chatID, userID, text)
+}
+
+// handleBroadcastDraftText captures the text of a pending broadcast, returns false if none is pending
+func (b *Bot) handleBroadcastDraftText(ctx context.Context, chatID int64, userID int64, text string) bool {
+	b.broadcastMu.Lock()
+	draft, ok := b.broadcastDrafts[userID]
+	b.broadcastMu.Unlock()
+
+	if !ok || draft.Text != "" {
+		return false
+	}
+
+	b.previewBroadcast(ctx, chatID, userID, text)
+	return true
+}
+
+func (b *Bot) handleCancelCommand(ctx context.Context, chatID int64, userID int64) {
+	if b.cancelSearchDraft(userID) {
+		b.sendMessage(ctx, chatID, "❌ Создание поиска отменено.")
+		return
+	}
+
+	b.broadcastMu.Lock()
+	_, ok := b.broadcastDrafts[userID]
+	delete(b.broadcastDrafts, userID)
+	b.broadcastMu.Unlock()
+
+	if !ok {
+		b.sendMessage(ctx, chatID, "Нечего отменять.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, "❌ Рассылка отменена.")
+}
+
+func (b *Bot) previewBroadcast(ctx context.Context, chatID int64, userID int64, text string) {
+	draft := &broadcastDraft{
+		ID:     strconv.FormatInt(time.Now().UnixNano(), 36),
+		ChatID: chatID,
+		Text:   text,
+	}
+
+	b.broadcastMu.Lock()
+	b.broadcastDrafts[userID] = draft
+	b.broadcastMu.Unlock()
+
+	b.sendMessage(ctx, chatID, "👀 *Предпросмотр рассылки:*")
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Отправить", fmt.Sprintf("broadcast_confirm:%s", draft.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отмена", fmt.Sprintf("broadcast_cancel:%s", draft.ID)),
+		),
+	)
+
+	if _, err := b.send(ctx, msg); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to send broadcast preview")
+		b.sendMessage(ctx, chatID, "❌ Не удалось отобразить предпросмотр. Проверьте разметку сообщения.")
+
+		b.broadcastMu.Lock()
+		delete(b.broadcastDrafts, userID)
+		b.broadcastMu.Unlock()
+	}
+}
+
+func (b *Bot) handleBroadcastConfirm(ctx context.Context, chatID int64, userID int64, draftID string) {
+	if !b.userService.IsAdmin(userID) {
+		return
+	}
+
+	b.broadcastMu.Lock()
+	draft, ok := b.broadcastDrafts[userID]
+	if ok && draft.ID == draftID {
+		delete(b.broadcastDrafts, userID)
+	}
+	b.broadcastMu.Unlock()
+
+	if !ok || draft.ID != draftID {
+		b.sendMessage(ctx, chatID, "⌛ Черновик рассылки устарел. Создайте новый через /broadcast.")
+		return
+	}
+
+	b.goBackground(ctx, func(ctx context.Context) {
+		b.runBroadcast(ctx, draft)
+	})
+}
+
+func (b *Bot) handleBroadcastCancel(ctx context.Context, chatID int64, userID int64, draftID string) {
+	b.broadcastMu.Lock()
+	draft, ok := b.broadcastDrafts[userID]
+	if ok && draft.ID == draftID {
+		delete(b.broadcastDrafts, userID)
+	}
+	b.broadcastMu.Unlock()
+
+	b.sendMessage(ctx, chatID, "❌ Рассылка отменена.")
+}
+
+// runBroadcast delivers a confirmed draft to all active users and reports progress
+func (b *Bot) runBroadcast(ctx context.Context, draft *broadcastDraft) {
+	progressID := 0
+	progressSent := false
+	report, err := b.Broadcast(ctx, draft.Text, func(report *BroadcastReport) {
+		if progressSent {
+			if progressID != 0 {
+				b.editBroadcastProgress(ctx, draft.ChatID, progressID, formatBroadcastProgress(report))
+			}
+			return
+		}
+
+		progressSent = true
+		progress, err := b.send(ctx, tgbotapi.NewMessage(draft.ChatID, formatBroadcastProgress(report)))
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Error("Failed to send broadcast progress message")
+		}
+		progressID = progress.MessageID
+	})
+	if ctx.Err() != nil {
+		logging.FromContext(ctx).WithField("sent", report.Sent+report.Failed).Warn("Broadcast interrupted by shutdown")
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get active users")
+		b.sendMessage(ctx, draft.ChatID, "❌ Ошибка при получении списка пользователей.")
+		return
+	}
+
+	if progressID != 0 {
+		b.editBroadcastProgress(ctx, draft.ChatID, progressID, formatBroadcastProgress(report))
+	}
+	b.sendMessage(ctx, draft.ChatID, formatBroadcastReport(report))
+}
+
+// Broadcast delivers text to all active users through the outbox and deactivates users that
+// blocked the bot. progress, if not nil, is called before the first message and then every few
+// seconds with the report so far. When ctx is cancelled it stops and returns the partial report
+// with ctx's error.
+func (b *Bot) Broadcast(ctx context.Context, text string, progress func(*BroadcastReport)) (*BroadcastReport, error) {
+	users, err := b.userService.GetAllActiveUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BroadcastReport{
+		Total:    len(users),
+		Failures: make(map[string]int),
+	}
+	if progress != nil {
+		progress(report)
+	}
+
+	metrics.OutboundQueueDepth.Add(float64(len(users)))
+	defer func() {
+		metrics.OutboundQueueDepth.Sub(float64(report.Total - report.Sent - report.Failed))
+	}()
+
+	lastProgress := time.Now()
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		err := b.deliverBroadcast(ctx, user.ID, text)
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		metrics.OutboundQueueDepth.Dec()
+
+		if err != nil {
+			reason := b.handleDeliveryFailure(ctx, "broadcast", user.ID, err)
+			report.Failed++
+			report.Failures[reason]++
+		} else {
+			report.Sent++
+			metrics.NotificationsSentTotal.WithLabelValues("broadcast").Inc()
+		}
+
+		if progress != nil && time.Since(lastProgress) >= broadcastProgressInterval {
+			progress(report)
+			lastProgress = time.Now()
+		}
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"total":  report.Total,
+		"sent":   report.Sent,
+		"failed": report.Failed,
+	}).Info("Broadcast finished")
+
+	return report, nil
+}
+
+// deliverBroadcast sends a broadcast message, waiting out Telegram flood limits
+func (b *Bot) deliverBroadcast(ctx context.Context, chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	return b.deliver(ctx, msg)
+}
+
+// deliver sends a message that is not a reply to the user, retrying when Telegram asks to slow down
+func (b *Bot) deliver(ctx context.Context, msg tgbotapi.Chattable) error {
+	var err error
+	for attempt := 0; attempt < broadcastMaxRetries; attempt++ {
+		_, err = b.send(ctx, msg)
+
+		var tgErr *tgbotapi.Error
+		if errors.As(err, &tgErr) && tgErr.Code == 429 && tgErr.RetryAfter > 0 {
+			select {
+			case <-time.After(time.Duration(tgErr.RetryAfter) * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		return err
+	}
+	return err
+}
+
+// handleDeliveryFailure records a failed delivery of the given kind and deactivates users who can
+// no longer be reached. It returns the failure reason.
+func (b *Bot) handleDeliveryFailure(ctx context.Context, kind string, userID int64, err error) string {
+	reason := broadcastFailureReason(err)
+	metrics.NotificationsFailedTotal.WithLabelValues(kind, reason).Inc()
+
+	logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+		"user_id": userID,
+		"kind":    kind,
+		"reason":  reason,
+	}).Warn("Failed to deliver message")
+
+	// Not a deactivation: the user is reachable again as soon as they write to the bot
+	if reason == failureBlocked || reason == failureDeactivated {
+		if err := b.userService.MarkBlocked(ctx, userID); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("Failed to mark unreachable user as blocked")
+		}
+	}
+	return reason
+}
+
+func (b *Bot) editBroadcastProgress(ctx context.Context, chatID int64, messageID int, text string) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	if _, err := b.send(ctx, edit); err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Failed to update broadcast progress")
+	}
+}
+
+const (
+	failureBlocked     = "blocked"
+	failureDeactivated = "deactivated"
+	failureNotFound    = "chat_not_found"
+	failureRateLimited = "rate_limited"
+	failureOther       = "other"
+)
+
+var failureReasonTitles = map[string]string{
+	failureBlocked:     "бот заблокирован пользователем",
+	failureDeactivated: "аккаунт удалён",
+	failureNotFound:    "чат не найден",
+	failureRateLimited: "превышен лимит Telegram",
+	failureOther:       "другие ошибки",
+}
+
+// broadcastFailureReason classifies a Telegram API error for the delivery report
+func broadcastFailureReason(err error) string {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return failureOther
+	}
+
+	message := strings.ToLower(tgErr.Message)
+	switch {
+	case tgErr.Code == 429:
+		return failureRateLimited
+	case strings.Contains(message, "deactivated"):
+		return failureDeactivated
+	case tgErr.Code == 403:
+		return failureBlocked
+	case strings.Contains(message, "chat not found"):
+		return failureNotFound
+	default:
+		return failureOther
+	}
+}
+
+func formatBroadcastProgress(report *BroadcastReport) string {
+	return fmt.Sprintf("📣 Отправлено %d/%d, ошибок %d", report.Sent+report.Failed, report.Total, report.Failed)
+}
+
+func formatBroadcastReport(report *BroadcastReport) string {
+	var message strings.Builder
+	message.WriteString("📣 *Рассылка завершена*\n\n")
+	message.WriteString(fmt.Sprintf("👥 Получателей: %d\n", report.Total))
+	message.WriteString(fmt.Sprintf("✅ Доставлено: %d\n", report.Sent))
+	message.WriteString(fmt.Sprintf("❌ Ошибок: %d\n", report.Failed))
+
+	if len(report.Failures) > 0 {
+		reasons := make([]string, 0, len(report.Failures))
+		for reason := range report.Failures {
+			reasons = append(reasons, reason)
+		}
+		sort.Slice(reasons, func(i, j int) bool {
+			return report.Failures[reasons[i]] > report.Failures[reasons[j]]
+		})
+
+		message.WriteString("\n*Причины ошибок:*\n")
+		for _, reason := range reasons {
+			message.WriteString(fmt.Sprintf("• %s: %d\n", failureReasonTitles[reason], report.Failures[reason]))
+		}
+	}
+
+	return message.String()
+}
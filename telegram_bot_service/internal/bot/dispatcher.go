@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// queueFullWarnInterval limits how often a saturated worker queue is reported
+const queueFullWarnInterval = 10 * time.Second
+
+// dispatcher is a bounded worker pool that shards updates by chat ID, so updates from one chat
+// are handled in order while different chats are processed in parallel
+type dispatcher struct {
+	queues []chan tgbotapi.Update
+	handle func(tgbotapi.Update)
+	wg     sync.WaitGroup
+
+	// mu keeps Stop from closing the queues while Dispatch is sending to them
+	mu      sync.RWMutex
+	stopped bool
+
+	warnMu       sync.Mutex
+	lastFullWarn time.Time
+}
+
+func newDispatcher(workers, queueSize int, handle func(tgbotapi.Update)) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	queues := make([]chan tgbotapi.Update, workers)
+	for i := range queues {
+		queues[i] = make(chan tgbotapi.Update, queueSize)
+	}
+
+	return &dispatcher{
+		queues: queues,
+		handle: handle,
+	}
+}
+
+// Start launches one goroutine per shard
+func (d *dispatcher) Start() {
+	for i, queue := range d.queues {
+		d.wg.Add(1)
+		go d.worker(i, queue)
+	}
+	logrus.WithField("workers", len(d.queues)).Info("Update workers started")
+}
+
+// Stop closes the queues and waits until queued updates are processed
+func (d *dispatcher) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+// Dispatch enqueues an update to its chat's shard, blocking while the shard is full.
+// It returns false without enqueueing once the dispatcher is stopped.
+func (d *dispatcher) Dispatch(update tgbotapi.Update) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.stopped {
+		return false
+	}
+
+	queue := d.queues[d.shard(update)]
+	select {
+	case queue <- update:
+	default:
+		d.warnQueueFull()
+		queue <- update
+	}
+	return true
+}
+
+// QueueDepth returns the number of updates waiting across all shards
+func (d *dispatcher) QueueDepth() int {
+	depth := 0
+	for _, queue := range d.queues {
+		depth += len(queue)
+	}
+	return depth
+}
+
+// Workers returns the size of the pool
+func (d *dispatcher) Workers() int {
+	return len(d.queues)
+}
+
+func (d *dispatcher) worker(id int, queue <-chan tgbotapi.Update) {
+	defer d.wg.Done()
+
+	for update := range queue {
+		d.safeHandle(id, update)
+	}
+}
+
+// safeHandle runs the handler and recovers from panics so one bad update can't stop a worker
+func (d *dispatcher) safeHandle(workerID int, update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithFields(logrus.Fields{
+				"worker":    workerID,
+				"update_id": update.UpdateID,
+				"panic":     r,
+				"stack":     string(debug.Stack()),
+			}).Error("Recovered from panic in update handler")
+		}
+	}()
+
+	d.handle(update)
+}
+
+func (d *dispatcher) shard(update tgbotapi.Update) int {
+	return int(uint64(updateChatID(update)) % uint64(len(d.queues)))
+}
+
+func (d *dispatcher) warnQueueFull() {
+	d.warnMu.Lock()
+	defer d.warnMu.Unlock()
+
+	if time.Since(d.lastFullWarn) < queueFullWarnInterval {
+		return
+	}
+	d.lastFullWarn = time.Now()
+
+	logrus.WithField("queue_depth", d.QueueDepth()).Warn("Update worker queue is full, applying backpressure")
+}
+
+// updateChatID returns the chat an update belongs to, falling back to the sender ID
+func updateChatID(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}
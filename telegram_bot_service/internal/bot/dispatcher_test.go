@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDispatcherHandlesQueuedUpdatesOnStop(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int
+	d := newDispatcher(2, 10, func(update tgbotapi.Update) {
+		mu.Lock()
+		handled = append(handled, update.UpdateID)
+		mu.Unlock()
+	})
+	d.Start()
+
+	for id := 1; id <= 5; id++ {
+		if !d.Dispatch(tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: int64(id)}}}) {
+			t.Fatalf("update %d rejected before Stop", id)
+		}
+	}
+	d.Stop()
+
+	if len(handled) != 5 {
+		t.Errorf("handled %d updates, want 5", len(handled))
+	}
+}
+
+func TestDispatchAfterStopIsRejected(t *testing.T) {
+	d := newDispatcher(1, 1, func(tgbotapi.Update) {})
+	d.Start()
+	d.Stop()
+
+	// A late webhook request must not send on a closed queue
+	if d.Dispatch(tgbotapi.Update{UpdateID: 1}) {
+		t.Error("Dispatch accepted an update after Stop")
+	}
+}
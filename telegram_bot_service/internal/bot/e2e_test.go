@@ -0,0 +1,188 @@
+package bot_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/bot"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/parsertest"
+	"telegram_bot_service/internal/repository"
+	"telegram_bot_service/internal/services"
+	"telegram_bot_service/internal/telegramtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const e2eTimeout = 5 * time.Second
+
+// TestNotifierEndToEnd runs the bot over HTTP against the fake Bot API and parser: a user
+// subscribes, the parser publishes a new listing, and the notification reaches Telegram
+func TestNotifierEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	tg := telegramtest.NewServer()
+	defer tg.Close()
+	parser := parsertest.NewServer(testListings[:1])
+	defer parser.Close()
+
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("TEST_TOKEN", tg.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := repository.NewMemoryUserRepository()
+	listings := repository.NewMemoryListingRepository()
+	cian := services.NewCianService(parser.URL)
+	history := services.NewListingHistoryService(cian, listings, nil)
+
+	// The listings already on the market are the baseline, they are never announced
+	baseline, err := history.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Record(ctx, baseline, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bot.NewWithAPI(
+		api,
+		cian,
+		services.NewUserService(users, nil),
+		services.NewFavoriteService(repository.NewMemoryFavoriteRepository()),
+		services.NewSubscriptionService(repository.NewMemorySubscriptionRepository(users)),
+		services.NewAccessService(repository.NewMemoryAccessRepository(), services.AccessModeOpen, nil, nil),
+		bot.Options{Workers: 2, QueueSize: 10, History: history, CheckInterval: time.Hour},
+	)
+	done := make(chan error, 1)
+	go func() { done <- b.Start() }()
+	defer func() {
+		b.Stop()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Start: %v", err)
+			}
+		case <-time.After(e2eTimeout):
+			t.Error("the bot didn't stop")
+		}
+	}()
+
+	tg.SendMessage(42, "/subscribe")
+	if _, err := tg.WaitForSent(42, 1, e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetListings(testListings)
+	b.SetCheckInterval(10 * time.Millisecond)
+
+	sent, err := tg.WaitForSent(42, 2, e2eTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification := sent[1]
+	if !strings.Contains(notification.Text, "Новое объявление") || !strings.Contains(notification.Text, "Студия") {
+		t.Fatalf("notification = %q", notification.Text)
+	}
+	if strings.Contains(notification.Text, "Сокольническая") {
+		t.Errorf("the baseline listing was announced: %q", notification.Text)
+	}
+
+	// The button under the notification works like the one in /listings
+	buttons := notification.Buttons()
+	if len(buttons) == 0 || buttons[0] != "fav_add:102" {
+		t.Fatalf("notification buttons = %v", buttons)
+	}
+	tg.PressButton(42, notification.MessageID, buttons[0])
+	if _, err := tg.WaitForSent(42, 3, e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBlockedUserIsNotifiedAfterReturning blocks the bot during a notification, then has the
+// user write again: the subscription survives and the next listing is delivered
+func TestBlockedUserIsNotifiedAfterReturning(t *testing.T) {
+	ctx := context.Background()
+	tg := telegramtest.NewServer()
+	defer tg.Close()
+	parser := parsertest.NewServer(testListings[:1])
+	defer parser.Close()
+
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("TEST_TOKEN", tg.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := repository.NewMemoryUserRepository()
+	cian := services.NewCianService(parser.URL)
+	history := services.NewListingHistoryService(cian, repository.NewMemoryListingRepository(), nil)
+	baseline, err := history.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Record(ctx, baseline, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bot.NewWithAPI(
+		api,
+		cian,
+		services.NewUserService(users, nil),
+		services.NewFavoriteService(repository.NewMemoryFavoriteRepository()),
+		services.NewSubscriptionService(repository.NewMemorySubscriptionRepository(users)),
+		services.NewAccessService(repository.NewMemoryAccessRepository(), services.AccessModeOpen, nil, nil),
+		bot.Options{Workers: 2, QueueSize: 10, History: history, CheckInterval: 10 * time.Millisecond},
+	)
+	done := make(chan error, 1)
+	go func() { done <- b.Start() }()
+	defer func() {
+		b.Stop()
+		select {
+		case <-done:
+		case <-time.After(e2eTimeout):
+			t.Error("the bot didn't stop")
+		}
+	}()
+
+	tg.SendMessage(42, "/subscribe")
+	if _, err := tg.WaitForSent(42, 1, e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	tg.FailChat(42, 403, "Forbidden: bot was blocked by the user")
+	parser.SetListings(testListings)
+	waitFor(t, "the user to be marked as blocked", func() bool {
+		user, err := users.GetUser(ctx, 42)
+		return err == nil && user.BlockedAt != nil
+	})
+
+	tg.RestoreChat(42)
+	tg.SendMessage(42, "/start")
+	if _, err := tg.WaitForSent(42, 2, e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	returned := models.Listing{ID: "103", Title: "1-комн. квартира, 38 м²", Price: "45 000 ₽/мес.", PriceValue: 45000, Address: "Москва, ул. Лесная, 5", URL: "https://cian.ru/rent/flat/103/", Metro: "Белорусская"}
+	parser.SetListings(append(testListings, returned))
+	sent, err := tg.WaitForSent(42, 3, e2eTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sent[2].Text, "Лесная") {
+		t.Errorf("notification after returning = %q", sent[2].Text)
+	}
+}
+
+// waitFor polls condition until it holds or the test times out
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(e2eTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
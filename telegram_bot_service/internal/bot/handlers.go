@@ -1,33 +1,51 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/metrics"
 	"telegram_bot_service/internal/models"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"github.com/sirupsen/logrus"
 )
 
-func (b *Bot) handleListingsCommand(chatID int64) {
-	listings, err := b.cianService.GetListings(false)
+func (b *Bot) handleListingsCommand(ctx context.Context, chatID int64, userID int64) {
+	listings, err := b.cianService.GetListings(ctx, false)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to get listings")
-		b.sendMessage(chatID, "❌ Ошибка при получении объявлений. Попробуйте позже.")
+		logging.FromContext(ctx).WithError(err).Error("Failed to get listings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении объявлений. Попробуйте позже.")
 		return
 	}
 
 	if len(listings) == 0 {
-		b.sendMessage(chatID, "📭 Объявления не найдены.")
+		b.sendMessage(ctx, chatID, "📭 Объявления не найдены.")
 		return
 	}
 
 	// Send listings with pagination
-	b.sendListingsPage(chatID, listings, 0)
+	b.sendListingsPage(ctx, chatID, userID, listings, 0, allListingsView)
 }
 
-func (b *Bot) sendListingsPage(chatID int64, listings []models.Listing, page int) {
+// listingsView describes a paginated list of listings
+type listingsView struct {
+	// title heads each page, followed by the range of shown listings
+	title string
+	// pageAction is the callback action of the navigation buttons
+	pageAction string
+	// refresh adds the button that reloads the listings
+	refresh bool
+	// save adds the button that saves the search shown
+	save bool
+}
+
+var allListingsView = listingsView{title: "Объявления", pageAction: "listings_page", refresh: true}
+
+func (b *Bot) sendListingsPage(ctx context.Context, chatID int64, userID int64, listings []models.Listing, page int, view listingsView) {
+	listings, scores := b.rankListings(ctx, userID, listings)
+
 	pageSize := 5
 	totalPages := (len(listings) + pageSize - 1) / pageSize
 
@@ -42,36 +60,39 @@ func (b *Bot) sendListingsPage(chatID int64, listings []models.Listing, page int
 	}
 
 	var message strings.Builder
-	message.WriteString(fmt.Sprintf("🏠 *Объявления (%d-%d из %d)*\n\n", start+1, end, len(listings)))
+	message.WriteString(fmt.Sprintf("🏠 *%s (%d-%d из %d)*\n\n", view.title, start+1, end, len(listings)))
 
 	for i := start; i < end; i++ {
 		listing := listings[i]
-		message.WriteString(b.formatListingForDisplay(&listing))
+		if score, ok := scores[listing.ID]; ok {
+			message.WriteString(formatScore(score))
+		}
+		message.WriteString(b.formatListingForDisplay(ctx, &listing))
 		message.WriteString("\n---\n\n")
 	}
 
 	// Create keyboard with navigation and favorite buttons
-	keyboard := b.createListingsKeyboard(listings[start:end], page, totalPages)
+	keyboard := b.createListingsKeyboard(listings[start:end], page, totalPages, view)
 
 	msg := tgbotapi.NewMessage(chatID, message.String())
 	msg.ParseMode = tgbotapi.ModeMarkdown
 	msg.ReplyMarkup = keyboard
 
-	if _, err := b.api.Send(msg); err != nil {
-		logrus.WithError(err).Error("Failed to send listings")
+	if _, err := b.send(ctx, msg); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to send listings")
 	}
 }
 
-func (b *Bot) handleFavoritesCommand(chatID int64, userID int64) {
-	favorites, err := b.favoriteService.GetUserFavorites(userID)
+func (b *Bot) handleFavoritesCommand(ctx context.Context, chatID int64, userID int64) {
+	favorites, err := b.favoriteService.GetUserFavorites(ctx, userID)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to get favorites")
-		b.sendMessage(chatID, "❌ Ошибка при получении избранного.")
+		logging.FromContext(ctx).WithError(err).Error("Failed to get favorites")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении избранного.")
 		return
 	}
 
 	if len(favorites) == 0 {
-		b.sendMessage(chatID, "⭐ У вас пока нет избранных объявлений.")
+		b.sendMessage(ctx, chatID, "⭐ У вас пока нет избранных объявлений.")
 		return
 	}
 
@@ -95,16 +116,16 @@ func (b *Bot) handleFavoritesCommand(chatID int64, userID int64) {
 	msg.ReplyMarkup = keyboard
 	msg.DisableWebPagePreview = true
 
-	if _, err := b.api.Send(msg); err != nil {
-		logrus.WithError(err).Error("Failed to send favorites")
+	if _, err := b.send(ctx, msg); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to send favorites")
 	}
 }
 
-func (b *Bot) handleSettingsCommand(chatID int64) {
-	settings, err := b.cianService.GetSettings()
+func (b *Bot) handleSettingsCommand(ctx context.Context, chatID int64) {
+	settings, err := b.cianService.GetSettings(ctx)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to get settings")
-		b.sendMessage(chatID, "❌ Ошибка при получении настроек.")
+		logging.FromContext(ctx).WithError(err).Error("Failed to get settings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении настроек.")
 		return
 	}
 
@@ -117,44 +138,90 @@ func (b *Bot) handleSettingsCommand(chatID int64) {
 
 	message.WriteString("\n💡 Для изменения настроек используйте команды или обратитесь к администратору.")
 
-	b.sendMessage(chatID, message.String())
+	b.sendMessage(ctx, chatID, message.String())
 }
 
-func (b *Bot) handleSubscribeCommand(chatID int64, userID int64) {
-	// TODO: Implement subscription logic
-	b.sendMessage(chatID, "🔔 Подписка на уведомления активирована! Вы будете получать уведомления о новых объявлениях.")
+func (b *Bot) handleSubscribeCommand(ctx context.Context, chatID int64, userID int64) {
+	if err := b.subscriptionService.Subscribe(ctx, userID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to subscribe user")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при оформлении подписки.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, "🔔 Подписка на уведомления активирована! Вы будете получать уведомления о новых объявлениях.")
 }
 
-func (b *Bot) handleUnsubscribeCommand(chatID int64, userID int64) {
-	// TODO: Implement unsubscription logic
-	b.sendMessage(chatID, "🔕 Подписка на уведомления отключена.")
+func (b *Bot) handleUnsubscribeCommand(ctx context.Context, chatID int64, userID int64) {
+	if err := b.subscriptionService.Unsubscribe(ctx, userID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to unsubscribe user")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при отмене подписки.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, "🔕 Подписка на уведомления отключена.\n\nСохранённые поиски работают отдельно, их можно приостановить в /searches.")
 }
 
-func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
+func (b *Bot) handleTextMessage(ctx context.Context, message *tgbotapi.Message) {
 	// Handle non-command text messages
 	chatID := message.Chat.ID
-	b.sendMessage(chatID, "Используйте команды для взаимодействия с ботом. Напишите /help для справки.")
+
+	if b.handleBroadcastDraftText(ctx, chatID, message.From.ID, message.Text) {
+		return
+	}
+	if b.handleSearchDraftText(ctx, chatID, message.From.ID, message.Text) {
+		return
+	}
+
+	b.sendMessage(ctx, chatID, "Используйте команды для взаимодействия с ботом. Напишите /help для справки.")
 }
 
-func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
 	chatID := query.Message.Chat.ID
 	userID := query.From.ID
 	data := query.Data
 
+	allowed, err := b.accessService.HasAccess(ctx, userID)
+	if err != nil || !allowed {
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Error("Failed to check user access")
+		}
+		callback := tgbotapi.NewCallback(query.ID, "🔒 Доступ ограничен")
+		if _, err := b.request(ctx, callback); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("Failed to acknowledge callback query")
+		}
+		return
+	}
+
+	if ok, reply := b.allowCallback(ctx, userID); !ok {
+		callback := tgbotapi.NewCallback(query.ID, reply)
+		if _, err := b.request(ctx, callback); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("Failed to acknowledge callback query")
+		}
+		return
+	}
+
 	// Acknowledge the callback query
 	callback := tgbotapi.NewCallback(query.ID, "")
-	if _, err := b.api.Request(callback); err != nil {
-		logrus.WithError(err).Error("Failed to acknowledge callback query")
+	if _, err := b.request(ctx, callback); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to acknowledge callback query")
+	}
+
+	if err := b.userService.ClearBlocked(ctx, userID); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to clear blocked state")
 	}
 
 	parts := strings.Split(data, ":")
+	metrics.CallbacksTotal.WithLabelValues(callbackMetricLabel(parts[0])).Inc()
+
 	if len(parts) < 2 {
 		// Handle single action callbacks
 		switch data {
 		case "refresh_listings":
-			b.handleRefreshListings(chatID)
+			b.handleRefreshListings(ctx, chatID, userID)
 		case "back_to_listings":
-			b.handleListingsCommand(chatID)
+			b.handleListingsCommand(ctx, chatID, userID)
+		case "search_save":
+			b.handleSaveSearch(ctx, chatID, userID)
 		}
 		return
 	}
@@ -164,24 +231,48 @@ func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
 
 	switch action {
 	case "fav_add":
-		b.handleAddToFavorites(chatID, userID, param)
+		b.handleAddToFavorites(ctx, chatID, userID, param)
 	case "fav_remove":
-		b.handleRemoveFromFavorites(chatID, userID, param)
+		b.handleRemoveFromFavorites(ctx, chatID, userID, param)
+	case "broadcast_confirm":
+		b.handleBroadcastConfirm(ctx, chatID, userID, param)
+	case "broadcast_cancel":
+		b.handleBroadcastCancel(ctx, chatID, userID, param)
 	case "listings_page":
 		if page, err := strconv.Atoi(param); err == nil {
 			// Get fresh listings and show page
-			if listings, err := b.cianService.GetListings(false); err == nil {
-				b.sendListingsPage(chatID, listings, page)
+			if listings, err := b.cianService.GetListings(ctx, false); err == nil {
+				b.sendListingsPage(ctx, chatID, userID, listings, page, allListingsView)
 			}
 		}
+	case "search_page":
+		if page, err := strconv.Atoi(param); err == nil {
+			b.handleSearchPage(ctx, chatID, userID, page)
+		}
+	case "saved_pause", "saved_resume", "saved_delete":
+		b.handleSavedSearchCallback(ctx, chatID, userID, action, param)
+	}
+}
+
+// knownCallbacks bounds the label values of the callbacks metric
+var knownCallbacks = map[string]bool{
+	"refresh_listings": true, "back_to_listings": true, "fav_add": true, "fav_remove": true,
+	"listings_page": true, "broadcast_confirm": true, "broadcast_cancel": true, "search_page": true,
+	"search_save": true, "saved_pause": true, "saved_resume": true, "saved_delete": true,
+}
+
+func callbackMetricLabel(action string) string {
+	if knownCallbacks[action] {
+		return action
 	}
+	return "unknown"
 }
 
-func (b *Bot) handleAddToFavorites(chatID int64, userID int64, listingID string) {
+func (b *Bot) handleAddToFavorites(ctx context.Context, chatID int64, userID int64, listingID string) {
 	// Get listing details from CIAN API
-	listings, err := b.cianService.GetListings(false)
+	listings, err := b.cianService.GetListings(ctx, false)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка при получении данных объявления.")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении данных объявления.")
 		return
 	}
 
@@ -194,44 +285,54 @@ func (b *Bot) handleAddToFavorites(chatID int64, userID int64, listingID string)
 	}
 
 	if targetListing == nil {
-		b.sendMessage(chatID, "❌ Объявление не найдено.")
+		b.sendMessage(ctx, chatID, "❌ Объявление не найдено.")
 		return
 	}
 
-	_, err = b.favoriteService.AddToFavorites(userID, targetListing, "")
+	_, err = b.favoriteService.AddToFavorites(ctx, userID, targetListing, "")
 	if err != nil {
-		logrus.WithError(err).Error("Failed to add to favorites")
-		b.sendMessage(chatID, "❌ Ошибка при добавлении в избранное.")
+		logging.FromContext(ctx).WithError(err).Error("Failed to add to favorites")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при добавлении в избранное.")
 		return
 	}
 
-	b.sendMessage(chatID, fmt.Sprintf("⭐ Объявление \"%s\" добавлено в избранное!", targetListing.Title))
+	b.sendMessage(ctx, chatID, fmt.Sprintf("⭐ Объявление \"%s\" добавлено в избранное!", targetListing.Title))
 }
 
-func (b *Bot) handleRemoveFromFavorites(chatID int64, userID int64, listingID string) {
-	err := b.favoriteService.RemoveFromFavorites(userID, listingID)
+func (b *Bot) handleRemoveFromFavorites(ctx context.Context, chatID int64, userID int64, listingID string) {
+	err := b.favoriteService.RemoveFromFavorites(ctx, userID, listingID)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to remove from favorites")
-		b.sendMessage(chatID, "❌ Ошибка при удалении из избранного.")
+		logging.FromContext(ctx).WithError(err).Error("Failed to remove from favorites")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при удалении из избранного.")
 		return
 	}
 
-	b.sendMessage(chatID, "🗑️ Объявление удалено из избранного.")
+	b.sendMessage(ctx, chatID, "🗑️ Объявление удалено из избранного.")
 }
 
-func (b *Bot) handleRefreshListings(chatID int64) {
-	listings, err := b.cianService.GetListings(true) // Force refresh
+func (b *Bot) handleRefreshListings(ctx context.Context, chatID int64, userID int64) {
+	allowed, force, reply := b.allowForceRefresh(ctx, userID)
+	if !allowed {
+		b.sendMessage(ctx, chatID, reply)
+		return
+	}
+
+	listings, err := b.cianService.GetListings(ctx, force)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to refresh listings")
-		b.sendMessage(chatID, "❌ Ошибка при обновлении объявлений.")
+		logging.FromContext(ctx).WithError(err).Error("Failed to refresh listings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при обновлении объявлений.")
 		return
 	}
 
 	if len(listings) == 0 {
-		b.sendMessage(chatID, "📭 Объявления не найдены.")
+		b.sendMessage(ctx, chatID, "📭 Объявления не найдены.")
 		return
 	}
 
-	b.sendMessage(chatID, "🔄 Объявления обновлены!")
-	b.sendListingsPage(chatID, listings, 0)
+	if force {
+		b.sendMessage(ctx, chatID, "🔄 Объявления обновлены!")
+	} else {
+		b.sendMessage(ctx, chatID, "🔄 Объявления недавно обновлялись, показываю актуальные.")
+	}
+	b.sendListingsPage(ctx, chatID, userID, listings, 0, allListingsView)
 }
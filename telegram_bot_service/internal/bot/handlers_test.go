@@ -0,0 +1,136 @@
+package bot_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"telegram_bot_service/internal/bot/bottest"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/parsertest"
+	"telegram_bot_service/internal/services"
+)
+
+var testListings = []models.Listing{
+	{ID: "101", Title: "2-комн. квартира, 54 м²", Price: "60 000 ₽/мес.", PriceValue: 60000, Address: "Москва, Сокольническая пл., 4", URL: "https://cian.ru/rent/flat/101/", Metro: "Сокольники"},
+	{ID: "102", Title: "Студия, 25 м²", Price: "38 000 ₽/мес.", PriceValue: 38000, Address: "Москва, ул. Бутлерова, 12", URL: "https://cian.ru/rent/flat/102/", Metro: "Калужская"},
+}
+
+// lastMessage returns the text of the last message in sent, failing the test when there is none
+func lastMessage(t *testing.T, sent []bottest.Sent) string {
+	t.Helper()
+
+	texts := bottest.Messages(sent)
+	if len(texts) == 0 {
+		t.Fatalf("no messages sent, requests: %+v", sent)
+	}
+	return texts[len(texts)-1]
+}
+
+func TestStartRegistersUser(t *testing.T) {
+	h := bottest.New(bottest.Config{})
+
+	reply := lastMessage(t, h.Message(42, "/start"))
+	if !strings.Contains(reply, "Добро пожаловать") {
+		t.Errorf("/start reply = %q", reply)
+	}
+
+	user, err := h.Users.GetUser(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("user not created: %v", err)
+	}
+	if user.Username != "user42" || !user.IsActive {
+		t.Errorf("user = %+v", user)
+	}
+}
+
+func TestHelpListsCommands(t *testing.T) {
+	h := bottest.New(bottest.Config{})
+
+	reply := lastMessage(t, h.Message(42, "/help"))
+	for _, command := range []string{"/listings", "/subscribe", "/unsubscribe", "/search"} {
+		if !strings.Contains(reply, command) {
+			t.Errorf("/help doesn't mention %s", command)
+		}
+	}
+}
+
+func TestSubscribeAndUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+	h := bottest.New(bottest.Config{})
+
+	if reply := lastMessage(t, h.Message(42, "/subscribe")); !strings.Contains(reply, "активирована") {
+		t.Errorf("/subscribe reply = %q", reply)
+	}
+	subscriptions, err := h.Subscriptions.ListUserSubscriptions(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 1 || !subscriptions[0].IsActive {
+		t.Fatalf("subscriptions after /subscribe = %+v", subscriptions)
+	}
+
+	if reply := lastMessage(t, h.Message(42, "/unsubscribe")); !strings.Contains(reply, "отключена") {
+		t.Errorf("/unsubscribe reply = %q", reply)
+	}
+	subscriptions, err = h.Subscriptions.ListUserSubscriptions(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 1 || subscriptions[0].IsActive {
+		t.Errorf("subscriptions after /unsubscribe = %+v", subscriptions)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	h := bottest.New(bottest.Config{})
+
+	if reply := lastMessage(t, h.Message(42, "/frobnicate")); !strings.Contains(reply, "Неизвестная команда") {
+		t.Errorf("reply = %q", reply)
+	}
+}
+
+func TestAllowlistRejectsUnknownUsers(t *testing.T) {
+	h := bottest.New(bottest.Config{AccessMode: services.AccessModeAllowlist, AllowedIDs: []int64{1}})
+
+	h.Message(42, "/start")
+	if _, err := h.Users.GetUser(context.Background(), 42); err == nil {
+		t.Error("a user outside the allowlist was registered")
+	}
+
+	sent := h.Press(42, "fav_add:101")
+	if len(sent) != 1 || sent[0].Method != "answerCallbackQuery" || !strings.Contains(sent[0].Text, "Доступ ограничен") {
+		t.Errorf("callback from a user outside the allowlist: %+v", sent)
+	}
+}
+
+func TestAddToFavoritesFromListings(t *testing.T) {
+	parser := parsertest.NewServer(testListings)
+	defer parser.Close()
+	h := bottest.New(bottest.Config{CianAPIURL: parser.URL})
+
+	var button string
+	for _, sent := range h.Message(42, "/listings") {
+		for _, data := range sent.Buttons() {
+			if data == "fav_add:101" {
+				button = data
+			}
+		}
+	}
+	if button == "" {
+		t.Fatal("/listings has no button to add listing 101 to favorites")
+	}
+
+	sent := h.Press(42, button)
+	if len(sent) == 0 || sent[0].Method != "answerCallbackQuery" {
+		t.Errorf("the callback was not answered first: %+v", sent)
+	}
+
+	favorites, err := h.Favorites.ListFavorites(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites) != 1 || favorites[0].ListingID != "101" {
+		t.Errorf("favorites = %+v", favorites)
+	}
+}
@@ -1,80 +1,118 @@
 package bot
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"telegram_bot_service/internal/health"
+	"telegram_bot_service/internal/metrics"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type HealthServer struct {
-	bot  *Bot
-	port string
+	bot    *Bot
+	port   int
+	mux    *http.ServeMux
+	server *http.Server
+	prober *health.Prober
 }
 
 type HealthResponse struct {
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Services  struct {
-		TelegramBot bool `json:"telegram_bot"`
-		CianAPI     bool `json:"cian_api"`
-		Database    bool `json:"database"`
-	} `json:"services"`
+	Status    string          `json:"status"`
+	Timestamp time.Time       `json:"timestamp"`
+	Services  []health.Status `json:"services"`
+	Updates   struct {
+		Workers    int `json:"workers"`
+		QueueDepth int `json:"queue_depth"`
+	} `json:"updates"`
 }
 
-func NewHealthServer(bot *Bot, port string) *HealthServer {
-	return &HealthServer{
-		bot:  bot,
-		port: port,
+func NewHealthServer(bot *Bot, port int, prober *health.Prober) *HealthServer {
+	hs := &HealthServer{
+		bot:    bot,
+		port:   port,
+		mux:    http.NewServeMux(),
+		prober: prober,
 	}
+
+	hs.mux.HandleFunc("/live", hs.livenessHandler)
+	hs.mux.HandleFunc("/ready", hs.readinessHandler)
+	hs.mux.HandleFunc("/health", hs.healthHandler)
+	hs.mux.Handle("/metrics", metrics.Handler())
+	hs.server = &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: hs.mux}
+
+	return hs
 }
 
-func (hs *HealthServer) Start() {
-	http.HandleFunc("/health", hs.healthHandler)
-	http.HandleFunc("/ready", hs.readinessHandler)
+// Handle registers an additional handler on the server, e.g. the Telegram webhook
+func (hs *HealthServer) Handle(pattern string, handler http.Handler) {
+	hs.mux.Handle(pattern, handler)
+}
 
+func (hs *HealthServer) Start() {
 	logrus.WithField("port", hs.port).Info("Starting health check server")
 
 	go func() {
-		if err := http.ListenAndServe(":"+hs.port, nil); err != nil {
+		if err := hs.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logrus.WithError(err).Error("Health server failed")
 		}
 	}()
 }
 
+// Shutdown stops accepting requests and waits for the ones in progress, including webhook
+// updates being dispatched
+func (hs *HealthServer) Shutdown(ctx context.Context) error {
+	return hs.server.Shutdown(ctx)
+}
+
+// livenessHandler reports that the process is up and serving HTTP
+func (hs *HealthServer) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "OK")
+}
+
+// readinessHandler reports whether critical dependencies passed their latest check
+func (hs *HealthServer) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if !hs.prober.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "Bot not ready")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Ready")
+}
+
+// healthHandler returns cached dependency details; it never calls dependencies itself
 func (hs *HealthServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
 		Status:    "healthy",
 		Timestamp: time.Now(),
+		Services:  hs.prober.Statuses(),
 	}
 
-	// Check Telegram Bot
-	response.Services.TelegramBot = hs.bot.api != nil
-
-	// Check CIAN API
-	if err := hs.bot.cianService.HealthCheck(); err != nil {
-		response.Services.CianAPI = false
+	for _, status := range response.Services {
+		if status.Healthy {
+			continue
+		}
+		if status.Critical {
+			response.Status = "unhealthy"
+			break
+		}
 		response.Status = "degraded"
-		logrus.WithError(err).Warn("CIAN API health check failed")
-	} else {
-		response.Services.CianAPI = true
 	}
 
-	// Check Database (simple check)
-	if _, err := hs.bot.userService.GetAllActiveUsers(); err != nil {
-		response.Services.Database = false
-		response.Status = "degraded"
-		logrus.WithError(err).Warn("Database health check failed")
-	} else {
-		response.Services.Database = true
-	}
+	response.Updates.Workers = hs.bot.dispatcher.Workers()
+	response.Updates.QueueDepth = hs.bot.QueueDepth()
 
 	w.Header().Set("Content-Type", "application/json")
 
 	statusCode := http.StatusOK
-	if response.Status != "healthy" {
+	if response.Status == "unhealthy" {
 		statusCode = http.StatusServiceUnavailable
 	}
 
@@ -82,14 +120,8 @@ func (hs *HealthServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func (hs *HealthServer) readinessHandler(w http.ResponseWriter, r *http.Request) {
-	// Simple readiness check
-	if hs.bot.api == nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		fmt.Fprintf(w, "Bot not ready")
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Ready")
+// Ping checks that the Telegram Bot API is reachable with the bot's token
+func (b *Bot) Ping(ctx context.Context) error {
+	_, err := b.api.GetMe()
+	return err
 }
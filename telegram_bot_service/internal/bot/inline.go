@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/services"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// inlinePageSize is the number of results per answer; Telegram accepts at most 50
+	inlinePageSize = 20
+	// inlineCacheTime is how long Telegram may reuse an answer, in seconds
+	inlineCacheTime = 60
+	// inlineMaxCaption is Telegram's limit on photo captions, longer cards are sent as text
+	inlineMaxCaption = 1024
+	// searchHelpParameter is the /start parameter of the button that opens the search help
+	searchHelpParameter = "search_help"
+)
+
+// inlineResult is a listing found by an inline query
+type inlineResult struct {
+	Listing *models.Listing
+	// Favorite is set for the user's favorites, which may be gone from the current listings
+	Favorite *models.Favorite
+}
+
+// handleInlineQuery answers "@bot 2к Сокольники" in any chat with the matching current listings
+// and favorites of the user, favorites first
+func (b *Bot) handleInlineQuery(ctx context.Context, query *tgbotapi.InlineQuery) {
+	userID := query.From.ID
+	answer := tgbotapi.InlineConfig{InlineQueryID: query.ID, CacheTime: inlineCacheTime, IsPersonal: true}
+
+	allowed, err := b.accessService.HasAccess(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to check user access")
+	}
+	if err != nil || !allowed {
+		b.answerInlineQuery(ctx, answer)
+		return
+	}
+	if ok, _ := b.allowCallback(ctx, userID); !ok {
+		answer.CacheTime = 0
+		b.answerInlineQuery(ctx, answer)
+		return
+	}
+
+	var search *services.SearchQuery
+	if text := strings.TrimSpace(query.Query); text != "" {
+		if search, err = services.ParseSearchQuery(text); err != nil {
+			var queryErr *services.QueryError
+			if errors.As(err, &queryErr) {
+				answer.SwitchPMText = "❌ " + queryErr.Message
+				answer.SwitchPMParameter = searchHelpParameter
+			} else {
+				logging.FromContext(ctx).WithError(err).Error("Failed to parse search query")
+			}
+			b.answerInlineQuery(ctx, answer)
+			return
+		}
+	}
+
+	results, err := b.inlineResults(ctx, userID, search)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to find listings for inline query")
+		answer.CacheTime = 0
+		b.answerInlineQuery(ctx, answer)
+		return
+	}
+
+	offset, _ := strconv.Atoi(query.Offset)
+	if offset < 0 || offset > len(results) {
+		offset = len(results)
+	}
+	end := min(offset+inlinePageSize, len(results))
+	if end < len(results) {
+		answer.NextOffset = strconv.Itoa(end)
+	}
+
+	answer.Results = make([]interface{}, 0, end-offset)
+	for _, result := range results[offset:end] {
+		answer.Results = append(answer.Results, b.inlineQueryResult(ctx, result))
+	}
+	if len(results) == 0 && offset == 0 {
+		answer.SwitchPMText = "📭 Ничего не найдено. Как искать?"
+		answer.SwitchPMParameter = searchHelpParameter
+	}
+	b.answerInlineQuery(ctx, answer)
+}
+
+// inlineResults finds the user's favorites and the current listings matching the query, a nil query
+// matches everything. Favorites no longer on the market only have a title to match.
+func (b *Bot) inlineResults(ctx context.Context, userID int64, query *services.SearchQuery) ([]inlineResult, error) {
+	listings, err := b.cianService.GetListings(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	favorites, err := b.favoriteService.GetUserFavorites(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if query != nil {
+		listings = query.FilterListings(listings)
+	}
+	listings, _ = b.rankListings(ctx, userID, listings)
+
+	current := make(map[string]*models.Listing, len(listings))
+	for i := range listings {
+		current[listings[i].ID] = &listings[i]
+	}
+
+	var results []inlineResult
+	shown := make(map[string]bool)
+	for i := range favorites {
+		favorite := &favorites[i]
+		listing, ok := current[favorite.ListingID]
+		if !ok {
+			listing = &models.Listing{ID: favorite.ListingID, Title: favorite.Title, Price: favorite.Price, URL: favorite.URL}
+			if query != nil && !query.Match(listing) {
+				continue
+			}
+		}
+		results = append(results, inlineResult{Listing: listing, Favorite: favorite})
+		shown[listing.ID] = true
+	}
+	for _, listing := range listings {
+		if !shown[listing.ID] {
+			results = append(results, inlineResult{Listing: current[listing.ID]})
+		}
+	}
+	return results, nil
+}
+
+// inlineQueryResult turns a listing into a photo when it has one and the card fits in a caption,
+// otherwise into an article
+func (b *Bot) inlineQueryResult(ctx context.Context, result inlineResult) interface{} {
+	listing := result.Listing
+	card := b.formatListingForDisplay(ctx, listing)
+	title := listing.Title
+	id := "l:" + listing.ID
+	if result.Favorite != nil {
+		title = "⭐ " + title
+		id = "f:" + listing.ID
+	}
+
+	description := priceText(listing)
+	if listing.Address != "" {
+		description += " · " + listing.Address
+	}
+
+	if len(listing.Photos) > 0 && utf8.RuneCountInString(card) <= inlineMaxCaption {
+		photo := tgbotapi.NewInlineQueryResultPhotoWithThumb(id, listing.Photos[0], listing.Photos[0])
+		photo.Title = title
+		photo.Description = description
+		photo.Caption = card
+		photo.ParseMode = tgbotapi.ModeMarkdown
+		return photo
+	}
+
+	article := tgbotapi.NewInlineQueryResultArticleMarkdown(id, title, card)
+	article.Description = description
+	article.URL = listing.URL
+	article.HideURL = true
+	if len(listing.Photos) > 0 {
+		article.ThumbURL = listing.Photos[0]
+	}
+	return article
+}
+
+func (b *Bot) answerInlineQuery(ctx context.Context, answer tgbotapi.InlineConfig) {
+	if _, err := b.request(ctx, answer); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to answer inline query")
+	}
+}
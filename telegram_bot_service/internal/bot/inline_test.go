@@ -0,0 +1,177 @@
+package bot_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"telegram_bot_service/internal/bot/bottest"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/parsertest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// inlineAnswer returns the single inline query answer in sent
+func inlineAnswer(t *testing.T, sent []bottest.Sent) tgbotapi.InlineConfig {
+	t.Helper()
+
+	if len(sent) != 1 || sent[0].Method != "answerInlineQuery" {
+		t.Fatalf("want one inline answer, got %+v", sent)
+	}
+	return sent[0].Request.(tgbotapi.InlineConfig)
+}
+
+// resultIDs returns the IDs of photo and article results in order
+func resultIDs(t *testing.T, answer tgbotapi.InlineConfig) []string {
+	t.Helper()
+
+	ids := make([]string, 0, len(answer.Results))
+	for _, result := range answer.Results {
+		switch r := result.(type) {
+		case tgbotapi.InlineQueryResultPhoto:
+			ids = append(ids, r.ID)
+		case tgbotapi.InlineQueryResultArticle:
+			ids = append(ids, r.ID)
+		default:
+			t.Fatalf("unexpected inline result %T", result)
+		}
+	}
+	return ids
+}
+
+func TestInlineShowsFavoritesFirst(t *testing.T) {
+	parser := parsertest.NewServer(testListings)
+	defer parser.Close()
+	h := bottest.New(bottest.Config{CianAPIURL: parser.URL})
+
+	ctx := context.Background()
+	for _, favorite := range []models.Favorite{
+		{UserID: 42, ListingID: "102", Title: testListings[1].Title, Price: testListings[1].Price, URL: testListings[1].URL},
+		{UserID: 42, ListingID: "999", Title: "1-комн. квартира у парка", Price: "45 000 ₽/мес.", URL: "https://cian.ru/rent/flat/999/"},
+	} {
+		if _, err := h.Favorites.CreateFavorite(ctx, &favorite); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	answer := inlineAnswer(t, h.Inline(42, "", ""))
+	// Favorites come newest first, then the rest of the current listings
+	if ids := strings.Join(resultIDs(t, answer), " "); ids != "f:999 f:102 l:101" {
+		t.Fatalf("results = %s, want the favorites first and each listing once", ids)
+	}
+
+	// A favorite gone from the current listings is built from the stored ID, title, price and URL
+	gone, ok := answer.Results[0].(tgbotapi.InlineQueryResultArticle)
+	if !ok {
+		t.Fatalf("result for a favorite without photos is %T", answer.Results[0])
+	}
+	content := gone.InputMessageContent.(tgbotapi.InputTextMessageContent).Text
+	if gone.Title != "⭐ 1-комн. квартира у парка" || gone.URL != "https://cian.ru/rent/flat/999/" || gone.Description != "45 000 ₽/мес." {
+		t.Errorf("favorite result = %+v", gone)
+	}
+	if !strings.Contains(content, "у парка") || !strings.Contains(content, "45 000") || !strings.Contains(content, "/flat/999/") {
+		t.Errorf("favorite card = %q", content)
+	}
+
+	// Such a favorite only has its title to match a query
+	answer = inlineAnswer(t, h.Inline(42, "парка", ""))
+	if ids := strings.Join(resultIDs(t, answer), " "); ids != "f:999" {
+		t.Errorf("results for a word from the title = %s, want f:999", ids)
+	}
+	answer = inlineAnswer(t, h.Inline(42, "метро Сокольники", ""))
+	if ids := strings.Join(resultIDs(t, answer), " "); ids != "l:101" {
+		t.Errorf("results for a metro station = %s, want l:101", ids)
+	}
+}
+
+func TestInlinePaging(t *testing.T) {
+	var listings []models.Listing
+	for i := 1; i <= 45; i++ {
+		listings = append(listings, models.Listing{
+			ID:         fmt.Sprint(1000 + i),
+			Title:      fmt.Sprintf("Квартира %d", i),
+			Price:      "50 000 ₽/мес.",
+			PriceValue: 50000,
+			URL:        fmt.Sprintf("https://cian.ru/rent/flat/%d/", 1000+i),
+		})
+	}
+	parser := parsertest.NewServer(listings)
+	defer parser.Close()
+	h := bottest.New(bottest.Config{CianAPIURL: parser.URL})
+
+	seen := make(map[string]bool)
+	pages := []struct {
+		offset, next string
+		results      int
+	}{
+		{"", "20", 20},
+		{"20", "40", 20},
+		{"40", "", 5},
+	}
+	for _, page := range pages {
+		answer := inlineAnswer(t, h.Inline(42, "", page.offset))
+		if len(answer.Results) != page.results || answer.NextOffset != page.next {
+			t.Fatalf("offset %q: %d results, next offset %q; want %d and %q",
+				page.offset, len(answer.Results), answer.NextOffset, page.results, page.next)
+		}
+		for _, id := range resultIDs(t, answer) {
+			if seen[id] {
+				t.Errorf("offset %q repeats %s", page.offset, id)
+			}
+			seen[id] = true
+		}
+	}
+	if len(seen) != len(listings) {
+		t.Errorf("pages show %d listings, want %d", len(seen), len(listings))
+	}
+
+	for _, offset := range []string{"45", "100", "-5"} {
+		answer := inlineAnswer(t, h.Inline(42, "", offset))
+		if len(answer.Results) != 0 || answer.NextOffset != "" || answer.SwitchPMText != "" {
+			t.Errorf("offset %q past the end: %+v", offset, answer)
+		}
+	}
+}
+
+func TestInlinePhotoOrArticle(t *testing.T) {
+	photo := "https://images.cian.ru/1.jpg"
+	listings := []models.Listing{
+		{ID: "1", Title: "Студия с фото", Price: "40 000 ₽/мес.", PriceValue: 40000, URL: "https://cian.ru/rent/flat/1/", Photos: []string{photo}},
+		{ID: "2", Title: strings.Repeat("Очень длинное название ", 50), Price: "40 000 ₽/мес.", PriceValue: 40000, URL: "https://cian.ru/rent/flat/2/", Photos: []string{photo}},
+		{ID: "3", Title: "Студия без фото", Price: "40 000 ₽/мес.", PriceValue: 40000, URL: "https://cian.ru/rent/flat/3/"},
+	}
+	parser := parsertest.NewServer(listings)
+	defer parser.Close()
+	h := bottest.New(bottest.Config{CianAPIURL: parser.URL})
+
+	results := make(map[string]interface{})
+	for _, result := range inlineAnswer(t, h.Inline(42, "", "")).Results {
+		switch r := result.(type) {
+		case tgbotapi.InlineQueryResultPhoto:
+			results[r.ID] = r
+		case tgbotapi.InlineQueryResultArticle:
+			results[r.ID] = r
+		}
+	}
+
+	if r, ok := results["l:1"].(tgbotapi.InlineQueryResultPhoto); !ok {
+		t.Errorf("listing with a photo and a short card is %T", results["l:1"])
+	} else if r.URL != photo || !strings.Contains(r.Caption, "Студия с фото") {
+		t.Errorf("photo result = %+v", r)
+	}
+
+	// The card doesn't fit in a caption, so the photo only becomes the thumbnail
+	if r, ok := results["l:2"].(tgbotapi.InlineQueryResultArticle); !ok {
+		t.Errorf("listing with a card longer than a caption is %T", results["l:2"])
+	} else if r.ThumbURL != photo {
+		t.Errorf("article thumbnail = %q, want the photo", r.ThumbURL)
+	}
+
+	if r, ok := results["l:3"].(tgbotapi.InlineQueryResultArticle); !ok {
+		t.Errorf("listing without photos is %T", results["l:3"])
+	} else if r.ThumbURL != "" {
+		t.Errorf("article thumbnail = %q, want none", r.ThumbURL)
+	}
+}
@@ -0,0 +1,34 @@
+package bot
+
+import (
+	"context"
+	"math"
+	"telegram_bot_service/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// registerMetrics exposes gauges computed from the bot's state on every scrape
+func (b *Bot) registerMetrics() {
+	metrics.RegisterGaugeFunc("active_users", "Number of active users.", func() float64 {
+		_, active, err := b.userService.CountUsers(context.Background())
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to count users for metrics")
+			return math.NaN()
+		}
+		return float64(active)
+	})
+
+	metrics.RegisterGaugeFunc("active_subscriptions", "Number of active subscriptions.", func() float64 {
+		count, err := b.subscriptionService.CountActiveSubscriptions(context.Background())
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to count subscriptions for metrics")
+			return math.NaN()
+		}
+		return float64(count)
+	})
+
+	metrics.RegisterGaugeFunc("update_queue_depth", "Number of incoming updates waiting for a worker.", func() float64 {
+		return float64(b.QueueDepth())
+	})
+}
@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/metrics"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/services"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// notifyMaxListings caps the listings one user receives per check; the rest are summarized
+	notifyMaxListings = 10
+	// notifyMaxDuplicates caps the links to other copies of a new listing
+	notifyMaxDuplicates = 5
+)
+
+// listingNotification is one message about a new or changed listing
+type listingNotification struct {
+	Listing *models.Listing
+	// Tag names the user's saved searches the listing matches, empty for the subscription to all listings
+	Tag string
+	// Header says what happened; the user's score and the listing card follow it
+	Header string
+	Card   string
+	// Score is the user's score of the listing, -1 when listings are not ranked
+	Score int
+}
+
+func (n listingNotification) text() string {
+	if n.Score < 0 {
+		return n.Tag + n.Header + n.Card
+	}
+	return n.Tag + n.Header + formatScore(n.Score) + n.Card
+}
+
+// startNotifier checks for new listings right away and then every check interval until the bot stops
+func (b *Bot) startNotifier() {
+	if b.options.History == nil {
+		return
+	}
+
+	logrus.WithField("interval", b.checkInterval()).Info("Starting listing notifier")
+
+	b.goBackground(context.Background(), func(stopped context.Context) {
+		for {
+			ctx := logging.WithRequestID(stopped, logging.NewRequestID())
+			if _, err := b.NotifyOnce(ctx); err != nil && stopped.Err() == nil {
+				logging.FromContext(ctx).WithError(err).Error("Listing check failed")
+			}
+
+			if !b.waitForCheck() {
+				return
+			}
+		}
+	})
+}
+
+// waitForCheck sleeps until the next check, starting over when the interval changes; it returns false once stopped
+func (b *Bot) waitForCheck() bool {
+	for {
+		timer := time.NewTimer(b.checkInterval())
+		select {
+		case <-timer.C:
+			return true
+		case <-b.notifyReset:
+			timer.Stop()
+		case <-b.stop:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+func (b *Bot) checkInterval() time.Duration {
+	b.notifyMu.Lock()
+	defer b.notifyMu.Unlock()
+	return b.options.CheckInterval
+}
+
+// SetCheckInterval changes how often a running bot checks for new listings
+func (b *Bot) SetCheckInterval(interval time.Duration) {
+	b.notifyMu.Lock()
+	b.options.CheckInterval = interval
+	b.notifyMu.Unlock()
+
+	select {
+	case b.notifyReset <- struct{}{}:
+	default:
+	}
+}
+
+// NotifyOnce runs one check: it fetches listings, records them in the history and notifies
+// subscribers about new listings
+func (b *Bot) NotifyOnce(ctx context.Context) (*services.ListingDiff, error) {
+	diff, err := b.options.History.Poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Recording first means a failed delivery is not retried, but a listing is never announced twice
+	if err := b.options.History.Record(ctx, diff, time.Now()); err != nil {
+		return nil, err
+	}
+
+	metrics.NewListingsTotal.Add(float64(len(diff.New)))
+	metrics.DuplicateListingsTotal.Add(float64(diff.DuplicateCount()))
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"listings":      len(diff.Listings),
+		"new":           len(diff.New),
+		"duplicates":    diff.DuplicateCount(),
+		"price_changes": len(diff.PriceChanges),
+		"baseline":      diff.Baseline,
+	}).Info("Checked listings")
+
+	if diff.Empty() {
+		return diff, nil
+	}
+
+	searches, err := b.subscriptionService.ActiveSearches(ctx)
+	if err != nil {
+		return diff, err
+	}
+
+	var scorer *services.Scorer
+	if b.options.Ranking != nil {
+		// All fetched listings are the market sample, so the medians don't depend on what happens to be new
+		if scorer, err = b.options.Ranking.NewScorer(ctx, diff.Listings); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("Failed to prepare listing ranking")
+		}
+	}
+
+	notifications := b.listingNotifications(ctx, diff)
+	var recipients []int64
+	userSearches := make(map[int64][]services.SavedSearch)
+	for _, search := range searches {
+		if _, ok := userSearches[search.UserID]; !ok {
+			recipients = append(recipients, search.UserID)
+		}
+		userSearches[search.UserID] = append(userSearches[search.UserID], search)
+	}
+
+	for _, userID := range recipients {
+		if ctx.Err() != nil {
+			return diff, ctx.Err()
+		}
+		matched := matchSearches(notifications, userSearches[userID])
+		if len(matched) == 0 {
+			continue
+		}
+		if !b.canNotify(ctx, userID) {
+			continue
+		}
+		b.notifyUser(ctx, userID, b.rankNotifications(ctx, userID, scorer, matched))
+	}
+
+	return diff, nil
+}
+
+// canNotify reports whether a user is still reachable and has access; a subscription outlives both
+func (b *Bot) canNotify(ctx context.Context, userID int64) bool {
+	user, err := b.userService.GetUser(ctx, userID)
+	if err != nil || !user.Reachable() {
+		return false
+	}
+	allowed, err := b.accessService.HasAccess(ctx, userID)
+	return err == nil && allowed
+}
+
+// matchSearches keeps the notifications about listings matching any of a user's saved searches
+// and tags them with the names of those searches, so a listing matching two searches comes once
+func matchSearches(notifications []listingNotification, searches []services.SavedSearch) []listingNotification {
+	var matched []listingNotification
+	for _, notification := range notifications {
+		var names []string
+		found := false
+		for i := range searches {
+			if !searches[i].Match(notification.Listing) {
+				continue
+			}
+			found = true
+			if searches[i].Name != "" {
+				names = append(names, escapeMarkdown(searches[i].Name))
+			}
+		}
+		if !found {
+			continue
+		}
+
+		if len(names) > 0 {
+			notification.Tag = fmt.Sprintf("🔖 *%s*\n", strings.Join(names, ", "))
+		}
+		matched = append(matched, notification)
+	}
+	return matched
+}
+
+// notifyUser delivers notifications to a user through the outbox, stopping at the first failure
+func (b *Bot) notifyUser(ctx context.Context, userID int64, notifications []listingNotification) {
+	for i, notification := range notifications {
+		if i == notifyMaxListings {
+			msg := tgbotapi.NewMessage(userID, fmt.Sprintf("…и ещё %d объявлений. Смотрите /listings", len(notifications)-i))
+			if err := b.deliver(ctx, msg); err != nil {
+				b.handleDeliveryFailure(ctx, "listing", userID, err)
+			}
+			return
+		}
+
+		msg := tgbotapi.NewMessage(userID, notification.text())
+		msg.ParseMode = tgbotapi.ModeMarkdown
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("⭐ В избранное", fmt.Sprintf("fav_add:%s", notification.Listing.ID)),
+			),
+		)
+
+		if err := b.deliver(ctx, msg); err != nil {
+			if ctx.Err() == nil {
+				b.handleDeliveryFailure(ctx, "listing", userID, err)
+			}
+			return
+		}
+		metrics.NotificationsSentTotal.WithLabelValues("listing").Inc()
+	}
+}
+
+// listingNotifications formats the messages about a diff, new listings first until they are ranked
+func (b *Bot) listingNotifications(ctx context.Context, diff *services.ListingDiff) []listingNotification {
+	notifications := make([]listingNotification, 0, len(diff.New))
+	for i := range diff.New {
+		listing := &diff.New[i]
+		notifications = append(notifications, listingNotification{
+			Listing: listing,
+			Header:  "🆕 *Новое объявление*\n\n",
+			Card:    b.formatListingForDisplay(ctx, listing) + formatDuplicates(diff.Duplicates[listing.ID]),
+			Score:   -1,
+		})
+	}
+	return notifications
+}
+
+// rankNotifications orders notifications by the user's score, best first, and shows the scores
+func (b *Bot) rankNotifications(ctx context.Context, userID int64, scorer *services.Scorer, notifications []listingNotification) []listingNotification {
+	if scorer == nil {
+		return notifications
+	}
+
+	settings, err := b.options.Ranking.Settings(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("user_id", userID).Error("Failed to get ranking settings")
+		return notifications
+	}
+
+	ranked := make([]listingNotification, len(notifications))
+	for i, notification := range notifications {
+		notification.Score = scorer.Score(settings, notification.Listing)
+		ranked[i] = notification
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// formatDuplicates lists other copies of the same flat, e.g. posted by several agents
+func formatDuplicates(duplicates []models.Listing) string {
+	if len(duplicates) == 0 {
+		return ""
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("\n\n👥 Также размещено другими (%d):", len(duplicates)))
+	for i := range duplicates {
+		if i == notifyMaxDuplicates {
+			message.WriteString(fmt.Sprintf("\n…и ещё %d", len(duplicates)-i))
+			break
+		}
+		message.WriteString(fmt.Sprintf("\n• [%s](%s)", escapeMarkdown(duplicates[i].Title), duplicates[i].URL))
+	}
+	return message.String()
+}
+
+// priceText returns the formatted price of a listing, falling back to the numeric one
+func priceText(listing *models.Listing) string {
+	if listing.Price != "" {
+		return listing.Price
+	}
+	return fmt.Sprintf("%d ₽", listing.PriceValue)
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultSendRate stays below Telegram's global limit of ~30 messages per second
+const defaultSendRate = 25
+
+// outbox paces all messages the bot sends. Replies, notifications and broadcasts take turns
+// in one queue, so together they stay below Telegram's global limit.
+type outbox struct {
+	mu       sync.Mutex
+	interval time.Duration
+	// next is the earliest time the next message may go out
+	next time.Time
+}
+
+// newOutbox creates an outbox sending up to rate messages per second; a non-positive rate
+// uses the default
+func newOutbox(rate int) *outbox {
+	if rate <= 0 {
+		rate = defaultSendRate
+	}
+	return &outbox{interval: time.Second / time.Duration(rate)}
+}
+
+// wait takes the next free slot in the queue and sleeps until it comes
+func (o *outbox) wait(ctx context.Context) error {
+	o.mu.Lock()
+	now := time.Now()
+	slot := o.next
+	if slot.Before(now) {
+		slot = now
+	}
+	o.next = slot.Add(o.interval)
+	o.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
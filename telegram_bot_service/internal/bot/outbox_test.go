@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOutboxPacesMessages(t *testing.T) {
+	o := newOutbox(100)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := o.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("6 messages at 100/s took %s, want at least 50ms", elapsed)
+	}
+}
+
+func TestOutboxWaitStopsWithContext(t *testing.T) {
+	o := newOutbox(1)
+	if err := o.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := o.wait(ctx); err == nil {
+		t.Error("wait returned before its slot without an error")
+	}
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/services"
+)
+
+var rankingComponentNames = map[string]string{
+	services.RankPricePerMeter: "Цена за м² относительно района",
+	services.RankFloor:         "Этаж",
+	services.RankMetro:         "Метро рядом",
+	services.RankAuthor:        "Собственник, а не агент",
+	services.RankCommission:    "Без комиссии",
+	services.RankFreshness:     "Свежесть",
+}
+
+var floorPreferenceNames = map[string]string{
+	services.FloorMiddle: "не первый и не последний",
+	services.FloorHigh:   "повыше",
+	services.FloorLow:    "пониже",
+}
+
+const rankingUsage = "Изменить настройки:\n" +
+	"`/ranking <критерий> <0-10>` - вес критерия, 0 - не учитывать\n" +
+	"`/ranking floor_pref middle|high|low` - какой этаж лучше\n" +
+	"`/ranking stations Сокольники, Арбатская` - любимые станции метро, `-` - сбросить\n" +
+	"`/ranking reset` - настройки по умолчанию"
+
+// rankListings orders listings by the user's score; without ranking, or when it fails,
+// the parser's order is kept and no scores are returned
+func (b *Bot) rankListings(ctx context.Context, userID int64, listings []models.Listing) ([]models.Listing, map[string]int) {
+	if b.options.Ranking == nil {
+		return listings, nil
+	}
+
+	ranked, scores, err := b.options.Ranking.Rank(ctx, userID, listings)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to rank listings")
+		return listings, nil
+	}
+	return ranked, scores
+}
+
+func formatScore(score int) string {
+	return fmt.Sprintf("🎯 Оценка: *%d/100*\n", score)
+}
+
+func (b *Bot) handleRankingCommand(ctx context.Context, chatID int64, userID int64, args string) {
+	if b.options.Ranking == nil {
+		b.sendMessage(ctx, chatID, "Ранжирование объявлений отключено.")
+		return
+	}
+
+	settings, err := b.options.Ranking.Settings(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get ranking settings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении настроек ранжирования.")
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendMessage(ctx, chatID, formatRankingSettings(settings)+"\n\n"+rankingUsage)
+		return
+	}
+
+	key := fields[0]
+	value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), key))
+	switch key {
+	case "reset":
+		settings = services.DefaultRankingSettings()
+	case "floor_pref":
+		if _, ok := floorPreferenceNames[value]; !ok {
+			b.sendMessage(ctx, chatID, "❌ Укажите middle, high или low.")
+			return
+		}
+		settings.Floor = value
+	case "stations":
+		settings.Metro = nil
+		if value != "-" {
+			for _, station := range strings.Split(value, ",") {
+				if station = strings.TrimSpace(station); station != "" {
+					settings.Metro = append(settings.Metro, station)
+				}
+			}
+		}
+	default:
+		if _, ok := rankingComponentNames[key]; !ok {
+			b.sendMessage(ctx, chatID, "❌ Неизвестный критерий.\n\n"+rankingUsage)
+			return
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 || weight > services.MaxRankingWeight {
+			b.sendMessage(ctx, chatID, fmt.Sprintf("❌ Вес должен быть числом от 0 до %d.", services.MaxRankingWeight))
+			return
+		}
+		settings.Weights[key] = weight
+	}
+
+	if err := b.options.Ranking.SaveSettings(ctx, userID, settings); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to save ranking settings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при сохранении настроек ранжирования.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, "✅ Настройки сохранены.\n\n"+formatRankingSettings(settings))
+}
+
+func formatRankingSettings(settings services.RankingSettings) string {
+	var message strings.Builder
+	message.WriteString("🎯 *Ранжирование объявлений*\n\n")
+	message.WriteString("Объявления в /listings и уведомлениях идут от лучших к худшим. Веса критериев:\n")
+	for _, component := range services.RankingComponents {
+		message.WriteString(fmt.Sprintf("• `%s` %s: *%d*\n", component, rankingComponentNames[component], settings.Weights[component]))
+	}
+
+	message.WriteString(fmt.Sprintf("\nЭтаж: %s\n", floorPreferenceNames[settings.Floor]))
+	if len(settings.Metro) > 0 {
+		message.WriteString(fmt.Sprintf("Станции метро: %s", escapeMarkdown(strings.Join(settings.Metro, ", "))))
+	} else {
+		message.WriteString("Станции метро: любые")
+	}
+	return message.String()
+}
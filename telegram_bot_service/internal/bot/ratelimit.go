@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/ratelimit"
+	"time"
+)
+
+// RateLimits configures per-user limits on incoming updates
+type RateLimits struct {
+	Messages              int
+	Callbacks             int
+	Window                time.Duration
+	RefreshCooldown       time.Duration
+	RefreshGlobalCooldown time.Duration
+}
+
+type rateLimiters struct {
+	messages      *ratelimit.Limiter
+	callbacks     *ratelimit.Limiter
+	refresh       *ratelimit.Limiter
+	refreshGlobal *ratelimit.Limiter
+	// notices throttles "too many requests" replies so spam does not turn into outgoing spam
+	notices *ratelimit.Limiter
+}
+
+// refreshGlobalKey is the single key of the limiter shared by all users
+const refreshGlobalKey = 0
+
+func newRateLimiters(limits RateLimits) *rateLimiters {
+	return &rateLimiters{
+		messages:      ratelimit.New(limits.Messages, limits.Window),
+		callbacks:     ratelimit.New(limits.Callbacks, limits.Window),
+		refresh:       ratelimit.New(1, limits.RefreshCooldown),
+		refreshGlobal: ratelimit.New(1, limits.RefreshGlobalCooldown),
+		notices:       ratelimit.New(1, limits.Window),
+	}
+}
+
+// SetRateLimits applies new limits to a running bot without forgetting recent activity
+func (b *Bot) SetRateLimits(limits RateLimits) {
+	b.limiters.messages.SetLimit(limits.Messages, limits.Window)
+	b.limiters.callbacks.SetLimit(limits.Callbacks, limits.Window)
+	b.limiters.refresh.SetLimit(1, limits.RefreshCooldown)
+	b.limiters.refreshGlobal.SetLimit(1, limits.RefreshGlobalCooldown)
+	b.limiters.notices.SetLimit(1, limits.Window)
+}
+
+// allowMessage checks the per-user message limit and warns the user once per window
+func (b *Bot) allowMessage(ctx context.Context, chatID int64, userID int64) bool {
+	if b.userService.IsAdmin(userID) {
+		return true
+	}
+
+	allowed, retryAfter := b.limiters.messages.Allow(userID)
+	if allowed {
+		return true
+	}
+
+	logging.FromContext(ctx).WithField("user_id", userID).Warn("Message rate limit exceeded")
+	if notify, _ := b.limiters.notices.Allow(userID); notify {
+		b.sendMessage(ctx, chatID, fmt.Sprintf("⏳ Слишком много запросов. Попробуйте через %s.", formatRetryAfter(retryAfter)))
+	}
+	return false
+}
+
+// allowCallback checks the per-user callback limit and returns the text to show when it is exceeded
+func (b *Bot) allowCallback(ctx context.Context, userID int64) (bool, string) {
+	if b.userService.IsAdmin(userID) {
+		return true, ""
+	}
+
+	allowed, retryAfter := b.limiters.callbacks.Allow(userID)
+	if allowed {
+		return true, ""
+	}
+
+	logging.FromContext(ctx).WithField("user_id", userID).Warn("Callback rate limit exceeded")
+	return false, fmt.Sprintf("⏳ Слишком часто. Попробуйте через %s.", formatRetryAfter(retryAfter))
+}
+
+// allowForceRefresh applies the refresh cooldowns. It returns false with a reply text when the
+// user must wait, and force=false when another refresh happened recently and cached data should be used.
+func (b *Bot) allowForceRefresh(ctx context.Context, userID int64) (allowed bool, force bool, reply string) {
+	if !b.userService.IsAdmin(userID) {
+		if ok, retryAfter := b.limiters.refresh.Allow(userID); !ok {
+			logging.FromContext(ctx).WithField("user_id", userID).Info("Refresh cooldown active")
+			return false, false, fmt.Sprintf("⏳ Обновлять объявления можно не так часто. Попробуйте через %s.", formatRetryAfter(retryAfter))
+		}
+	}
+
+	if ok, _ := b.limiters.refreshGlobal.Allow(refreshGlobalKey); !ok {
+		return true, false, ""
+	}
+
+	return true, true, ""
+}
+
+// formatRetryAfter renders a wait duration as whole seconds, rounding up
+func formatRetryAfter(d time.Duration) string {
+	return fmt.Sprintf("%d с", int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,63 @@
+package bot_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/bot"
+	"telegram_bot_service/internal/bot/bottest"
+	"telegram_bot_service/internal/parsertest"
+)
+
+func TestMessageRateLimitReply(t *testing.T) {
+	h := bottest.New(bottest.Config{
+		AdminIDs: []int64{1},
+		Options:  bot.Options{RateLimits: bot.RateLimits{Messages: 2, Window: time.Minute}},
+	})
+
+	h.Message(42, "/help")
+	h.Message(42, "/help")
+
+	reply := lastMessage(t, h.Message(42, "/help"))
+	if !strings.Contains(reply, "Слишком много запросов") || !strings.Contains(reply, " с.") {
+		t.Errorf("throttled reply = %q", reply)
+	}
+
+	// The warning is sent once per window, further messages are dropped silently
+	if sent := h.Message(42, "/help"); len(sent) != 0 {
+		t.Errorf("second throttled message got replies: %+v", sent)
+	}
+
+	if reply := lastMessage(t, h.Message(43, "/help")); strings.Contains(reply, "Слишком много запросов") {
+		t.Error("another user was throttled")
+	}
+
+	for i := 0; i < 3; i++ {
+		h.Message(1, "/help")
+	}
+	if reply := lastMessage(t, h.Message(1, "/help")); strings.Contains(reply, "Слишком много запросов") {
+		t.Error("an admin was throttled")
+	}
+}
+
+func TestCallbackRateLimitReply(t *testing.T) {
+	parser := parsertest.NewServer(testListings)
+	defer parser.Close()
+	h := bottest.New(bottest.Config{
+		CianAPIURL: parser.URL,
+		Options:    bot.Options{RateLimits: bot.RateLimits{Callbacks: 1, Window: time.Minute}},
+	})
+	h.Message(42, "/listings")
+
+	h.Press(42, "fav_add:101")
+	sent := h.Press(42, "fav_add:102")
+
+	if len(sent) != 1 || sent[0].Method != "answerCallbackQuery" || !strings.Contains(sent[0].Text, "Слишком часто") {
+		t.Fatalf("throttled callback got %+v", sent)
+	}
+	if favorites, err := h.Favorites.ListFavorites(context.Background(), 42); err != nil || len(favorites) != 1 {
+		t.Errorf("favorites after a throttled press = %+v, %v", favorites, err)
+	}
+}
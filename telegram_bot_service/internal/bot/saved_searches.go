@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// searchDraft is a saved search being created; the query is asked first unless it comes from /search
+type searchDraft struct {
+	// Query is nil until the user sends it
+	Query *services.SearchQuery
+	// AllListings is set when the user asked for all listings instead of a query
+	AllListings bool
+}
+
+func (d *searchDraft) hasQuery() bool {
+	return d.Query != nil || d.AllListings
+}
+
+// allListingsWords ask for a saved search without a query
+var allListingsWords = map[string]bool{"все": true, "всё": true, "-": true}
+
+const savedSearchesUsage = "Управление поисками:\n" +
+	"/searches new - создать поиск по шагам\n" +
+	"/searches rename <номер> <название> - переименовать\n" +
+	"/searches pause <номер>, /searches resume <номер> - приостановить и возобновить уведомления\n" +
+	"/searches delete <номер> - удалить\n" +
+	"Поиск можно сохранить и из результатов /search."
+
+func (b *Bot) handleSavedSearchesCommand(ctx context.Context, chatID int64, userID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendSavedSearches(ctx, chatID, userID)
+		return
+	}
+
+	switch fields[0] {
+	case "new":
+		b.startSearchDraft(ctx, chatID, userID, &searchDraft{})
+		return
+	case "rename", "pause", "resume", "delete":
+	default:
+		b.sendPlainText(ctx, chatID, savedSearchesUsage)
+		return
+	}
+
+	if len(fields) < 2 || (fields[0] == "rename" && len(fields) < 3) {
+		b.sendPlainText(ctx, chatID, savedSearchesUsage)
+		return
+	}
+	search := b.savedSearchByNumber(ctx, chatID, userID, fields[1])
+	if search == nil {
+		return
+	}
+
+	switch fields[0] {
+	case "rename":
+		b.renameSavedSearch(ctx, chatID, userID, search, strings.Join(fields[2:], " "))
+	case "pause":
+		b.setSavedSearchActive(ctx, chatID, userID, search.ID, false)
+	case "resume":
+		b.setSavedSearchActive(ctx, chatID, userID, search.ID, true)
+	case "delete":
+		b.deleteSavedSearch(ctx, chatID, userID, search.ID)
+	}
+}
+
+// savedSearchByNumber finds a search by its number in the /searches list, replying when there is none
+func (b *Bot) savedSearchByNumber(ctx context.Context, chatID int64, userID int64, number string) *services.SavedSearch {
+	searches, err := b.subscriptionService.ListSearches(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to list saved searches")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении сохранённых поисков.")
+		return nil
+	}
+
+	index, err := strconv.Atoi(number)
+	if err != nil || index < 1 || index > len(searches) {
+		b.sendMessage(ctx, chatID, "❌ Нет поиска с таким номером. Список поисков: /searches")
+		return nil
+	}
+	return &searches[index-1]
+}
+
+func (b *Bot) sendSavedSearches(ctx context.Context, chatID int64, userID int64) {
+	searches, err := b.subscriptionService.ListSearches(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to list saved searches")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении сохранённых поисков.")
+		return
+	}
+
+	if len(searches) == 0 {
+		b.sendPlainText(ctx, chatID, "🔖 Сохранённых поисков пока нет.\n\n"+savedSearchesUsage)
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("🔖 *Сохранённые поиски (%d из %d)*\n\n", len(searches), services.MaxSavedSearches))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, search := range searches {
+		state, toggle := "🔔", tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("⏸ %d", i+1), fmt.Sprintf("saved_pause:%d", search.ID))
+		if !search.Active {
+			state, toggle = "⏸", tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("▶️ %d", i+1), fmt.Sprintf("saved_resume:%d", search.ID))
+		}
+		message.WriteString(fmt.Sprintf("%d. %s *%s*\n   %s\n", i+1, state, escapeMarkdown(search.Name), formatSavedQuery(&search)))
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			toggle,
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 %d", i+1), fmt.Sprintf("saved_delete:%d", search.ID)),
+		))
+	}
+	message.WriteString("\n" + savedSearchesUsage)
+
+	msg := tgbotapi.NewMessage(chatID, message.String())
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := b.send(ctx, msg); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to send saved searches")
+	}
+}
+
+func formatSavedQuery(search *services.SavedSearch) string {
+	if search.Query == nil {
+		return "все новые объявления"
+	}
+	return "`" + strings.ReplaceAll(search.Query.String(), "`", "'") + "`"
+}
+
+// startSearchDraft asks for the missing parts of a new saved search
+func (b *Bot) startSearchDraft(ctx context.Context, chatID int64, userID int64, draft *searchDraft) {
+	b.searchMu.Lock()
+	b.searchDrafts[userID] = draft
+	b.searchMu.Unlock()
+
+	if !draft.hasQuery() {
+		b.sendPlainText(ctx, chatID, "🔖 Новый поиск, шаг 1 из 2. Отправьте запрос, например:\n"+
+			strings.Join(services.SearchExamples, "\n")+
+			"\n\nЧтобы получать все новые объявления, отправьте «все». Для отмены используйте /cancel.")
+		return
+	}
+	b.sendPlainText(ctx, chatID, "🔖 Как назвать поиск? Например: «Для себя» или «Для мамы». Для отмены используйте /cancel.")
+}
+
+// handleSearchDraftText takes the next answer of a pending saved search, returns false if none is pending
+func (b *Bot) handleSearchDraftText(ctx context.Context, chatID int64, userID int64, text string) bool {
+	b.searchMu.Lock()
+	draft, ok := b.searchDrafts[userID]
+	b.searchMu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	if !draft.hasQuery() {
+		next := &searchDraft{}
+		if allListingsWords[strings.ToLower(strings.TrimSpace(text))] {
+			next.AllListings = true
+		} else {
+			query, err := services.ParseSearchQuery(text)
+			if err != nil {
+				var queryErr *services.QueryError
+				if !errors.As(err, &queryErr) {
+					logging.FromContext(ctx).WithError(err).Error("Failed to parse search query")
+					b.sendMessage(ctx, chatID, "❌ Ошибка при разборе запроса.")
+					return true
+				}
+				b.sendPlainText(ctx, chatID, formatQueryError(queryErr)+"\nОтправьте исправленный запрос или /cancel.")
+				return true
+			}
+			next.Query = query
+		}
+		b.startSearchDraft(ctx, chatID, userID, next)
+		return true
+	}
+
+	search, err := b.subscriptionService.SaveSearch(ctx, userID, text, draft.Query)
+	if err != nil {
+		if reply, ok := savedSearchErrorText(err); ok {
+			b.sendPlainText(ctx, chatID, reply)
+			if errors.Is(err, services.ErrTooManySearches) {
+				b.cancelSearchDraft(userID)
+			}
+			return true
+		}
+		logging.FromContext(ctx).WithError(err).Error("Failed to save search")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при сохранении поиска.")
+		return true
+	}
+
+	b.cancelSearchDraft(userID)
+	b.sendPlainText(ctx, chatID, fmt.Sprintf("✅ Поиск «%s» сохранён. Уведомления о подходящих объявлениях будут приходить с его названием. Все поиски: /searches", search.Name))
+	return true
+}
+
+// cancelSearchDraft drops a pending saved search, returns false if none was pending
+func (b *Bot) cancelSearchDraft(userID int64) bool {
+	b.searchMu.Lock()
+	defer b.searchMu.Unlock()
+
+	_, ok := b.searchDrafts[userID]
+	delete(b.searchDrafts, userID)
+	return ok
+}
+
+// handleSaveSearch starts saving the user's last /search query
+func (b *Bot) handleSaveSearch(ctx context.Context, chatID int64, userID int64) {
+	b.searchMu.Lock()
+	query := b.searches[userID]
+	b.searchMu.Unlock()
+
+	if query == nil {
+		b.sendMessage(ctx, chatID, "Поиск устарел, повторите /search.")
+		return
+	}
+	b.startSearchDraft(ctx, chatID, userID, &searchDraft{Query: query})
+}
+
+func (b *Bot) renameSavedSearch(ctx context.Context, chatID int64, userID int64, search *services.SavedSearch, name string) {
+	if err := b.subscriptionService.RenameSearch(ctx, userID, search.ID, name); err != nil {
+		b.replySavedSearchError(ctx, chatID, err, "Failed to rename saved search")
+		return
+	}
+	b.sendPlainText(ctx, chatID, fmt.Sprintf("✏️ Поиск «%s» переименован в «%s».", search.Name, strings.Join(strings.Fields(name), " ")))
+}
+
+func (b *Bot) setSavedSearchActive(ctx context.Context, chatID int64, userID int64, id uint, active bool) {
+	if err := b.subscriptionService.SetSearchActive(ctx, userID, id, active); err != nil {
+		b.replySavedSearchError(ctx, chatID, err, "Failed to update saved search")
+		return
+	}
+
+	if active {
+		b.sendMessage(ctx, chatID, "🔔 Уведомления поиска возобновлены.")
+	} else {
+		b.sendMessage(ctx, chatID, "⏸ Уведомления поиска приостановлены.")
+	}
+}
+
+func (b *Bot) deleteSavedSearch(ctx context.Context, chatID int64, userID int64, id uint) {
+	if err := b.subscriptionService.DeleteSearch(ctx, userID, id); err != nil {
+		b.replySavedSearchError(ctx, chatID, err, "Failed to delete saved search")
+		return
+	}
+	b.sendMessage(ctx, chatID, "🗑️ Поиск удалён.")
+}
+
+// handleSavedSearchCallback handles the buttons of the /searches list
+func (b *Bot) handleSavedSearchCallback(ctx context.Context, chatID int64, userID int64, action, param string) {
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return
+	}
+
+	switch action {
+	case "saved_pause":
+		b.setSavedSearchActive(ctx, chatID, userID, uint(id), false)
+	case "saved_resume":
+		b.setSavedSearchActive(ctx, chatID, userID, uint(id), true)
+	case "saved_delete":
+		b.deleteSavedSearch(ctx, chatID, userID, uint(id))
+	}
+}
+
+func (b *Bot) replySavedSearchError(ctx context.Context, chatID int64, err error, logMessage string) {
+	if reply, ok := savedSearchErrorText(err); ok {
+		b.sendPlainText(ctx, chatID, reply)
+		return
+	}
+	logging.FromContext(ctx).WithError(err).Error(logMessage)
+	b.sendMessage(ctx, chatID, "❌ Ошибка при изменении поиска.")
+}
+
+// savedSearchErrorText explains the errors caused by the user's input
+func savedSearchErrorText(err error) (string, bool) {
+	switch {
+	case errors.Is(err, services.ErrSearchNotFound):
+		return "❌ Поиск не найден. Список поисков: /searches", true
+	case errors.Is(err, services.ErrSearchNameTaken):
+		return "❌ Поиск с таким названием уже есть. Придумайте другое название.", true
+	case errors.Is(err, services.ErrInvalidSearchName):
+		return fmt.Sprintf("❌ Название должно быть непустым и не длиннее %d символов. Попробуйте ещё раз.", services.MaxSearchNameLength), true
+	case errors.Is(err, services.ErrTooManySearches):
+		return fmt.Sprintf("❌ Можно сохранить не больше %d поисков. Удалите ненужные: /searches", services.MaxSavedSearches), true
+	}
+	return "", false
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var searchResultsView = listingsView{title: "Результаты поиска", pageAction: "search_page", save: true}
+
+const searchUsage = "🔎 Поиск по текущим объявлениям: /search <запрос>\n\n" +
+	"Что можно указать:\n" +
+	"• комнаты: 2к, 1-2к, студия\n" +
+	"• цену: до 60000, от 40 тыс, 40000-60000, цена<60000\n" +
+	"• метро и район: метро Сокольники, Преображенская площадь; район Хамовники\n" +
+	"• этаж: этаж>3, этаж 3-10, не первый, не последний\n" +
+	"• площадь: площадь>=40, от 40м, 30-50м\n" +
+	"• без комиссии, собственник\n" +
+	"Остальные слова ищутся в тексте объявления."
+
+func (b *Bot) handleSearchCommand(ctx context.Context, chatID int64, userID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		b.sendPlainText(ctx, chatID, searchUsage+"\n\nПримеры:\n/search "+strings.Join(services.SearchExamples, "\n/search "))
+		return
+	}
+
+	query, err := services.ParseSearchQuery(args)
+	if err != nil {
+		var queryErr *services.QueryError
+		if !errors.As(err, &queryErr) {
+			logging.FromContext(ctx).WithError(err).Error("Failed to parse search query")
+			b.sendMessage(ctx, chatID, "❌ Ошибка при разборе запроса.")
+			return
+		}
+		b.sendPlainText(ctx, chatID, formatQueryError(queryErr))
+		return
+	}
+
+	b.searchMu.Lock()
+	b.searches[userID] = query
+	b.searchMu.Unlock()
+
+	b.sendSearchResults(ctx, chatID, userID, query, 0)
+}
+
+func (b *Bot) handleSearchPage(ctx context.Context, chatID int64, userID int64, page int) {
+	b.searchMu.Lock()
+	query := b.searches[userID]
+	b.searchMu.Unlock()
+
+	if query == nil {
+		b.sendMessage(ctx, chatID, "Поиск устарел, повторите /search.")
+		return
+	}
+	b.sendSearchResults(ctx, chatID, userID, query, page)
+}
+
+// sendSearchResults filters the current listings by the query; the listings come from the parser's
+// cache, so pages of one search stay consistent between checks
+func (b *Bot) sendSearchResults(ctx context.Context, chatID int64, userID int64, query *services.SearchQuery, page int) {
+	listings, err := b.cianService.GetListings(ctx, false)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get listings")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при получении объявлений. Попробуйте позже.")
+		return
+	}
+
+	matched := query.FilterListings(listings)
+	if len(matched) == 0 {
+		b.sendPlainText(ctx, chatID, fmt.Sprintf("📭 По запросу «%s» ничего не найдено.", query))
+		return
+	}
+
+	b.sendListingsPage(ctx, chatID, userID, matched, page, searchResultsView)
+}
+
+func formatQueryError(err *services.QueryError) string {
+	text := "❌ Не понял запрос: " + err.Message
+	if err.Suggestion != "" {
+		text += "\n💡 " + err.Suggestion
+	}
+	return text + "\n\nНапишите /search без запроса, чтобы увидеть подсказку."
+}
+
+// sendPlainText sends text without markdown, for messages that quote what the user typed
+func (b *Bot) sendPlainText(ctx context.Context, chatID int64, text string) {
+	if _, err := b.send(ctx, tgbotapi.NewMessage(chatID, text)); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to send message")
+	}
+}
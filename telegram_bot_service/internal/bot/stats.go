@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"telegram_bot_service/internal/charts"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/services"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// statsChart is a rendered chart with its caption
+type statsChart struct {
+	name    string
+	png     []byte
+	caption string
+}
+
+func (b *Bot) handleStatsCommand(ctx context.Context, chatID int64) {
+	if b.options.Market == nil {
+		b.sendMessage(ctx, chatID, "Статистика рынка отключена.")
+		return
+	}
+
+	stats, err := b.options.Market.Stats(ctx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to compute market statistics")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при подсчёте статистики.")
+		return
+	}
+	if stats.Listings == 0 {
+		b.sendMessage(ctx, chatID, "📊 Пока недостаточно данных: статистика появится после нескольких проверок объявлений.")
+		return
+	}
+
+	b.sendMessage(ctx, chatID, formatStatsSummary(stats))
+
+	statsCharts, err := renderStatsCharts(stats)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to render market charts")
+		b.sendMessage(ctx, chatID, "❌ Ошибка при построении графиков.")
+		return
+	}
+	if err := b.sendCharts(ctx, chatID, statsCharts); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to send market charts")
+	}
+}
+
+func formatStatsSummary(stats *services.MarketStats) string {
+	var message strings.Builder
+	message.WriteString("📊 *Рынок аренды за 30 дней*\n\n")
+	message.WriteString(fmt.Sprintf("🏠 Квартир на рынке: %d\n", stats.Listings))
+
+	if len(stats.DailyNew) > 0 {
+		var added float64
+		for _, day := range stats.DailyNew {
+			added += day.Value
+		}
+		message.WriteString(fmt.Sprintf("🆕 Новых в день: %.1f\n", added/float64(len(stats.DailyNew))))
+	}
+
+	if stats.Delisted > 0 {
+		message.WriteString(fmt.Sprintf("⏱ Среднее время на рынке: %s (по %d снятым объявлениям)\n", formatDays(stats.TimeOnMarket), stats.Delisted))
+	} else {
+		message.WriteString("⏱ Среднее время на рынке: пока нет снятых объявлений\n")
+	}
+
+	if trend := stats.PriceTrend; len(trend) > 1 {
+		message.WriteString(fmt.Sprintf("📈 Медиана аренды: %s → %s ₽ (%s)\n",
+			formatThousands(int(trend[0].Value)), formatThousands(int(trend[len(trend)-1].Value)),
+			formatChange(trend[len(trend)-1].Value/trend[0].Value-1)))
+	}
+	return message.String()
+}
+
+// renderStatsCharts renders the charts that have data; legends are in the captions
+// because the charts can only draw numbers
+func renderStatsCharts(stats *services.MarketStats) ([]statsChart, error) {
+	var result []statsChart
+	add := func(name string, render func([]charts.Point) ([]byte, error), points []charts.Point, caption string) error {
+		if len(points) == 0 {
+			return nil
+		}
+		png, err := render(points)
+		if err != nil {
+			return err
+		}
+		result = append(result, statsChart{name: name, png: png, caption: caption})
+		return nil
+	}
+
+	var points []charts.Point
+	caption := "🏠 Медиана аренды по числу комнат (0 - студия)"
+	for _, group := range stats.RoomMedians {
+		points = append(points, charts.Point{Label: group.Name, Value: float64(group.Median)})
+		caption += fmt.Sprintf("\n• %s: %s ₽ (%d)", roomsText(group.Name), formatThousands(group.Median), group.Count)
+	}
+	if err := add("rooms.png", charts.BarChart, points, caption); err != nil {
+		return nil, err
+	}
+
+	points = nil
+	caption = "📍 Медиана аренды по районам"
+	for i, group := range stats.DistrictMedians {
+		points = append(points, charts.Point{Label: fmt.Sprint(i + 1), Value: float64(group.Median)})
+		caption += fmt.Sprintf("\n%d. %s: %s ₽ (%d)", i+1, group.Name, formatThousands(group.Median), group.Count)
+	}
+	if err := add("districts.png", charts.BarChart, points, caption); err != nil {
+		return nil, err
+	}
+
+	if err := add("new.png", charts.BarChart, dailyPoints(stats.DailyNew), "🆕 Новые объявления по дням"); err != nil {
+		return nil, err
+	}
+	if err := add("trend.png", charts.LineChart, dailyPoints(stats.PriceTrend), "📈 Медиана аренды по дням, ₽"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// sendCharts sends charts as one album, or as a photo when there is just one
+func (b *Bot) sendCharts(ctx context.Context, chatID int64, statsCharts []statsChart) error {
+	switch len(statsCharts) {
+	case 0:
+		return nil
+	case 1:
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: statsCharts[0].name, Bytes: statsCharts[0].png})
+		photo.Caption = statsCharts[0].caption
+		_, err := b.send(ctx, photo)
+		return err
+	}
+
+	media := make([]interface{}, 0, len(statsCharts))
+	for _, chart := range statsCharts {
+		photo := tgbotapi.NewInputMediaPhoto(tgbotapi.FileBytes{Name: chart.name, Bytes: chart.png})
+		photo.Caption = chart.caption
+		media = append(media, photo)
+	}
+	// An album is answered with an array of messages, which Send can't decode
+	_, err := b.request(ctx, tgbotapi.NewMediaGroup(chatID, media))
+	return err
+}
+
+func dailyPoints(values []services.DailyValue) []charts.Point {
+	points := make([]charts.Point, 0, len(values))
+	for _, value := range values {
+		points = append(points, charts.Point{Label: value.Day.Format("02.01"), Value: value.Value})
+	}
+	return points
+}
+
+func roomsText(rooms string) string {
+	if rooms == "0" {
+		return "студия"
+	}
+	return rooms + "-комн."
+}
+
+func formatDays(d time.Duration) string {
+	days := d.Hours() / 24
+	if days < 1 {
+		return fmt.Sprintf("%.0f ч", math.Max(1, d.Hours()))
+	}
+	return fmt.Sprintf("%.1f дн.", days)
+}
+
+func formatChange(change float64) string {
+	switch percent := math.Round(change * 100); {
+	case percent > 0:
+		return fmt.Sprintf("+%.0f%%", percent)
+	case percent < 0:
+		return fmt.Sprintf("%.0f%%", percent)
+	default:
+		return "без изменений"
+	}
+}
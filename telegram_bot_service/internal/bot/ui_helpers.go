@@ -1,19 +1,25 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"telegram_bot_service/internal/logging"
 	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/services"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // formatListingForDisplay formats a listing for display in Telegram
-func (b *Bot) formatListingForDisplay(listing *models.Listing) string {
+func (b *Bot) formatListingForDisplay(ctx context.Context, listing *models.Listing) string {
 	var message strings.Builder
 
 	message.WriteString(fmt.Sprintf("🏠 *%s*\n", escapeMarkdown(listing.Title)))
 	message.WriteString(fmt.Sprintf("💰 *%s*\n", listing.Price))
+	message.WriteString(b.formatPricePerMeter(ctx, listing))
 
 	if listing.Address != "" {
 		message.WriteString(fmt.Sprintf("📍 %s\n", escapeMarkdown(listing.Address)))
@@ -52,8 +58,68 @@ func (b *Bot) formatListingForDisplay(listing *models.Listing) string {
 	return message.String()
 }
 
+// formatPricePerMeter shows the price per square meter and how it compares with similar listings
+func (b *Bot) formatPricePerMeter(ctx context.Context, listing *models.Listing) string {
+	comparison := &services.PriceComparison{PricePerMeter: services.PricePerMeter(listing)}
+	if b.options.Market != nil {
+		var err error
+		if comparison, err = b.options.Market.Compare(ctx, listing); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("Failed to compare listing with the market")
+			comparison = &services.PriceComparison{PricePerMeter: services.PricePerMeter(listing)}
+		}
+	}
+	if comparison == nil || comparison.PricePerMeter <= 0 {
+		return ""
+	}
+
+	text := fmt.Sprintf("📊 %s ₽/м²", formatThousands(int(math.Round(comparison.PricePerMeter))))
+	if comparison.Median <= 0 {
+		return text + "\n"
+	}
+
+	percent := int(math.Round(comparison.Deviation() * 100))
+	switch {
+	case percent < 0:
+		text += fmt.Sprintf(", на %d%% ниже медианы", -percent)
+	case percent > 0:
+		text += fmt.Sprintf(", на %d%% выше медианы", percent)
+	default:
+		text += ", на уровне медианы"
+	}
+	return text + " " + marketGroupText(comparison) + "\n"
+}
+
+// marketGroupText describes the listings a listing was compared with
+func marketGroupText(comparison *services.PriceComparison) string {
+	switch comparison.Group {
+	case services.MarketMetro:
+		return "у м. " + escapeMarkdown(comparison.Name)
+	case services.MarketDistrict:
+		return "в районе " + escapeMarkdown(comparison.Name)
+	case services.MarketRooms:
+		if comparison.Name == "0" {
+			return "среди студий"
+		}
+		return fmt.Sprintf("среди %s-комн.", comparison.Name)
+	}
+	return ""
+}
+
+// formatThousands formats n with spaces between groups of digits, e.g. 1 250 000
+func formatThousands(n int) string {
+	digits := strconv.Itoa(n)
+	var result strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			result.WriteByte(' ')
+		}
+		result.WriteRune(digit)
+	}
+	return result.String()
+}
+
 // createListingsKeyboard creates inline keyboard for listings
-func (b *Bot) createListingsKeyboard(listings []models.Listing, currentPage, totalPages int) tgbotapi.InlineKeyboardMarkup {
+func (b *Bot) createListingsKeyboard(listings []models.Listing, currentPage, totalPages int, view listingsView) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	// Add favorite buttons for each listing
@@ -69,20 +135,27 @@ func (b *Bot) createListingsKeyboard(listings []models.Listing, currentPage, tot
 	var navButtons []tgbotapi.InlineKeyboardButton
 
 	if currentPage > 0 {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Предыдущая", fmt.Sprintf("listings_page:%d", currentPage-1)))
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Предыдущая", fmt.Sprintf("%s:%d", view.pageAction, currentPage-1)))
 	}
 
 	if currentPage < totalPages-1 {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Следующая ➡️", fmt.Sprintf("listings_page:%d", currentPage+1)))
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Следующая ➡️", fmt.Sprintf("%s:%d", view.pageAction, currentPage+1)))
 	}
 
 	if len(navButtons) > 0 {
 		rows = append(rows, navButtons)
 	}
 
+	if view.save {
+		saveButton := tgbotapi.NewInlineKeyboardButtonData("💾 Сохранить поиск", "search_save")
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{saveButton})
+	}
+
 	// Add refresh button
-	refreshButton := tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить", "refresh_listings")
-	rows = append(rows, []tgbotapi.InlineKeyboardButton{refreshButton})
+	if view.refresh {
+		refreshButton := tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить", "refresh_listings")
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{refreshButton})
+	}
 
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
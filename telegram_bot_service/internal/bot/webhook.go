@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+
+	// webhookSecretHeader carries the secret_token passed to setWebhook
+	webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// WebhookHandler serves Telegram webhook requests and feeds updates into the worker pool
+func (b *Bot) WebhookHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !b.validWebhookSecret(r) {
+			logrus.WithField("remote_addr", r.RemoteAddr).Warn("Rejected webhook request with invalid secret token")
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		update, err := b.api.HandleUpdate(r)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to decode webhook update")
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		// Telegram retries the update later, e.g. on the next instance after a restart
+		if !b.dispatcher.Dispatch(*update) {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (b *Bot) validWebhookSecret(r *http.Request) bool {
+	secret := b.options.WebhookSecret
+	if secret == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), []byte(secret)) == 1
+}
+
+// runWebhook registers the webhook with Telegram and waits until the bot is stopped
+func (b *Bot) runWebhook() error {
+	if b.options.WebhookSecret == "" {
+		logrus.Warn("WEBHOOK_SECRET is not set, webhook requests are not authenticated")
+	}
+
+	if err := b.registerWebhook(); err != nil {
+		return err
+	}
+
+	logrus.WithField("path", b.options.WebhookPath).Info("Receiving updates via webhook")
+	<-b.stop
+	return nil
+}
+
+// registerWebhook calls setWebhook directly, since the library's WebhookConfig has no secret_token
+func (b *Bot) registerWebhook() error {
+	url := strings.TrimRight(b.options.WebhookURL, "/") + b.options.WebhookPath
+
+	params := tgbotapi.Params{"url": url}
+	params.AddNonEmpty("secret_token", b.options.WebhookSecret)
+
+	if _, err := b.api.MakeRequest("setWebhook", params); err != nil {
+		return err
+	}
+
+	logrus.WithField("url", url).Info("Webhook registered")
+	return nil
+}
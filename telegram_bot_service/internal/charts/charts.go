@@ -0,0 +1,208 @@
+// Package charts renders simple bar and line charts as PNG images without external services.
+// Labels support digits and a few symbols only; titles and legends belong in the photo caption.
+package charts
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	width        = 800
+	height       = 480
+	marginLeft   = 90
+	marginRight  = 24
+	marginTop    = 24
+	marginBottom = 48
+	// yTicks is the approximate number of horizontal grid lines
+	yTicks = 5
+	// maxXLabels is the most labels that fit under the x axis
+	maxXLabels = 12
+)
+
+var (
+	backgroundColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	gridColor       = color.RGBA{0xe4, 0xe4, 0xe4, 0xff}
+	axisColor       = color.RGBA{0x55, 0x55, 0x55, 0xff}
+	textColor       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	barColor        = color.RGBA{0x4c, 0x72, 0xb0, 0xff}
+	lineColor       = color.RGBA{0xdd, 0x84, 0x52, 0xff}
+)
+
+// ErrNoData is returned for a chart without values
+var ErrNoData = errors.New("chart has no data")
+
+// Point is a labeled value: a bar of a bar chart or a point of a line chart
+type Point struct {
+	// Label is shown under the x axis; only digits and . : - % k m are drawn
+	Label string
+	Value float64
+}
+
+// BarChart renders points as vertical bars
+func BarChart(points []Point) ([]byte, error) {
+	if len(points) == 0 {
+		return nil, ErrNoData
+	}
+
+	img, plot, top := newCanvas(points)
+	slot := float64(plot.Dx()) / float64(len(points))
+	for i, point := range points {
+		barWidth := int(math.Max(1, slot*0.7))
+		left := plot.Min.X + int(float64(i)*slot+(slot-float64(barWidth))/2)
+		fillRect(img, image.Rect(left, yPosition(plot, top, point.Value), left+barWidth, plot.Max.Y), barColor)
+	}
+	drawXLabels(img, plot, points, func(i int) int {
+		return plot.Min.X + int((float64(i)+0.5)*slot)
+	})
+	return encode(img)
+}
+
+// LineChart renders points connected by a line, evenly spaced along the x axis
+func LineChart(points []Point) ([]byte, error) {
+	if len(points) == 0 {
+		return nil, ErrNoData
+	}
+
+	img, plot, top := newCanvas(points)
+	x := func(i int) int {
+		if len(points) == 1 {
+			return plot.Min.X + plot.Dx()/2
+		}
+		return plot.Min.X + i*plot.Dx()/(len(points)-1)
+	}
+	for i := range points {
+		px, py := x(i), yPosition(plot, top, points[i].Value)
+		if i > 0 {
+			drawLine(img, x(i-1), yPosition(plot, top, points[i-1].Value), px, py, lineColor)
+		}
+		fillRect(img, image.Rect(px-3, py-3, px+4, py+4), lineColor)
+	}
+	drawXLabels(img, plot, points, x)
+	return encode(img)
+}
+
+// newCanvas draws the background, the grid and the y axis labels; it returns the plot area
+// and the value at its top
+func newCanvas(points []Point) (*image.RGBA, image.Rectangle, float64) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: backgroundColor}, image.Point{}, draw.Src)
+	plot := image.Rect(marginLeft, marginTop, width-marginRight, height-marginBottom)
+
+	maxValue := 0.0
+	for _, point := range points {
+		maxValue = math.Max(maxValue, point.Value)
+	}
+	step := niceStep(maxValue / yTicks)
+	top := math.Max(step, math.Ceil(maxValue/step)*step)
+
+	for value := 0.0; value <= top+step/2; value += step {
+		y := yPosition(plot, top, value)
+		fillRect(img, image.Rect(plot.Min.X, y, plot.Max.X, y+1), gridColor)
+		label := FormatValue(value)
+		drawText(img, plot.Min.X-10-textWidth(label), y-textHeight/2, label, textColor)
+	}
+
+	fillRect(img, image.Rect(plot.Min.X-1, plot.Min.Y, plot.Min.X+1, plot.Max.Y+1), axisColor)
+	fillRect(img, image.Rect(plot.Min.X-1, plot.Max.Y, plot.Max.X, plot.Max.Y+2), axisColor)
+	return img, plot, top
+}
+
+// drawXLabels draws the labels of the points centered at x(i), skipping some when they don't fit
+func drawXLabels(img *image.RGBA, plot image.Rectangle, points []Point, x func(i int) int) {
+	every := (len(points) + maxXLabels - 1) / maxXLabels
+	for i, point := range points {
+		if i%every != 0 {
+			continue
+		}
+		drawText(img, x(i)-textWidth(point.Label)/2, plot.Max.Y+12, point.Label, textColor)
+	}
+}
+
+func yPosition(plot image.Rectangle, top, value float64) int {
+	return plot.Max.Y - int(math.Round(value/top*float64(plot.Dy())))
+}
+
+// niceStep rounds a raw grid step up to 1, 2 or 5 times a power of ten
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, multiple := range []float64{1, 2, 5} {
+		if raw <= multiple*magnitude {
+			return multiple * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// FormatValue formats an axis value compactly, e.g. 75000 as 75k and 1500000 as 1.5m
+func FormatValue(value float64) string {
+	switch {
+	case value >= 1e6:
+		return trimZeros(strconv.FormatFloat(value/1e6, 'f', 1, 64)) + "m"
+	case value >= 1e3:
+		return trimZeros(strconv.FormatFloat(value/1e3, 'f', 1, 64)) + "k"
+	default:
+		return trimZeros(strconv.FormatFloat(value, 'f', 1, 64))
+	}
+}
+
+func trimZeros(number string) string {
+	return strings.TrimSuffix(number, ".0")
+}
+
+func fillRect(img *image.RGBA, rect image.Rectangle, c color.Color) {
+	draw.Draw(img, rect, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+// drawLine draws a 3 pixel wide line with Bresenham's algorithm
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	for err := dx + dy; ; {
+		fillRect(img, image.Rect(x0-1, y0-1, x0+2, y0+2), c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
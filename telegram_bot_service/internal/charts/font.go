@@ -0,0 +1,68 @@
+package charts
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+	// fontScale enlarges the glyphs, each font pixel becomes a fontScale x fontScale square
+	fontScale = 2
+	// glyphSpacing is the gap between glyphs in image pixels
+	glyphSpacing = 2
+)
+
+// glyphs is a 5x7 bitmap font with just what axis labels need: digits and a few symbols.
+// Each row is a bit mask, the most significant of the five bits is the leftmost pixel.
+var glyphs = map[rune][glyphHeight]uint8{
+	'0': {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1': {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2': {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3': {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	'4': {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5': {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6': {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8': {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9': {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	'.': {0b00000, 0b00000, 0b00000, 0b00000, 0b00000, 0b01100, 0b01100},
+	':': {0b00000, 0b01100, 0b01100, 0b00000, 0b01100, 0b01100, 0b00000},
+	'-': {0b00000, 0b00000, 0b00000, 0b11111, 0b00000, 0b00000, 0b00000},
+	'%': {0b11000, 0b11001, 0b00010, 0b00100, 0b01000, 0b10011, 0b00011},
+	'k': {0b10000, 0b10000, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010},
+	'm': {0b00000, 0b00000, 0b11010, 0b10101, 0b10101, 0b10001, 0b10001},
+	' ': {},
+}
+
+// textWidth returns the width of text in image pixels
+func textWidth(text string) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return n*(glyphWidth*fontScale+glyphSpacing) - glyphSpacing
+}
+
+// textHeight is the height of a line of text in image pixels
+const textHeight = glyphHeight * fontScale
+
+// drawText draws text with its top left corner at (x, y); runes without a glyph are drawn as spaces
+func drawText(img *image.RGBA, x, y int, text string, c color.Color) {
+	for _, r := range text {
+		glyph := glyphs[r]
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				fillRect(img, image.Rect(
+					x+col*fontScale, y+row*fontScale,
+					x+(col+1)*fontScale, y+(row+1)*fontScale,
+				), c)
+			}
+		}
+		x += glyphWidth*fontScale + glyphSpacing
+	}
+}
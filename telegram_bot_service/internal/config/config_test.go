@@ -0,0 +1,247 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/config"
+)
+
+// writeConfig writes a config file with the given name to a temporary directory
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnv unsets settings the tests read from files, an empty variable counts as unset
+func clearEnv(t *testing.T, keys ...string) {
+	t.Helper()
+
+	for _, key := range keys {
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	clearEnv(t, "TELEGRAM_TOKEN", "CHECK_INTERVAL", "ADMIN_IDS", "DEDUP_PHOTO_HASH", "HEALTH_CHECK_PORT")
+
+	files := map[string]string{
+		"config.yaml": `
+telegram_token: "123:abc"
+check_interval: 5m
+admin_ids: [1, 2]
+dedup_photo_hash: true
+health_check_port: 9090
+`,
+		"config.yml": `
+TELEGRAM_TOKEN: "123:abc"
+CHECK_INTERVAL: 5m
+ADMIN_IDS: "1,2"
+DEDUP_PHOTO_HASH: true
+HEALTH_CHECK_PORT: 9090
+`,
+		"config.toml": `
+telegram_token = "123:abc"
+check_interval = "5m"
+admin_ids = [1, 2]
+dedup_photo_hash = true
+health_check_port = 9090
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := config.Load(writeConfig(t, name, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.TelegramToken != "123:abc" || cfg.CheckInterval != 5*time.Minute || !cfg.DedupPhotoHash || cfg.HealthCheckPort != 9090 {
+				t.Errorf("loaded %+v", cfg)
+			}
+			if !reflect.DeepEqual(cfg.AdminIDs, []int64{1, 2}) {
+				t.Errorf("AdminIDs = %v, want [1 2]", cfg.AdminIDs)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"unsupported format", "config.json", `{}`, "unsupported format"},
+		{"malformed YAML", "config.yaml", "telegram_token: [", "parse config file"},
+		{"malformed TOML", "config.toml", "telegram_token = ", "parse config file"},
+		{"nested value", "config.yaml", "database:\n  url: x\n", "unsupported value"},
+		{"unknown key", "config.yaml", "telegram_tokn: x\n", "unknown settings telegram_tokn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Load(writeConfig(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("a missing config file was accepted")
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	clearEnv(t, "CHECK_INTERVAL", "LOG_LEVEL")
+	t.Setenv("TELEGRAM_TOKEN", "456:env")
+	t.Setenv("ADMIN_IDS", "7")
+
+	path := writeConfig(t, "config.yaml", `
+telegram_token: "123:file"
+admin_ids: [1, 2]
+check_interval: 5m
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.TelegramToken != "456:env" {
+		t.Errorf("TelegramToken = %q, want the environment value", cfg.TelegramToken)
+	}
+	if !reflect.DeepEqual(cfg.AdminIDs, []int64{7}) {
+		t.Errorf("AdminIDs = %v, want the environment value", cfg.AdminIDs)
+	}
+	if cfg.CheckInterval != 5*time.Minute {
+		t.Errorf("CheckInterval = %s, want the file value", cfg.CheckInterval)
+	}
+	if cfg.LogLevel != "info" {
+		t.Errorf("LogLevel = %q, want the default", cfg.LogLevel)
+	}
+}
+
+func TestLoadReportsAllMalformedValues(t *testing.T) {
+	t.Setenv("CHECK_INTERVAL", "10")
+	t.Setenv("HEALTH_CHECK_PORT", "http")
+	t.Setenv("ADMIN_IDS", "1,@admin")
+
+	_, err := config.Load("")
+	if err == nil {
+		t.Fatal("malformed values were accepted")
+	}
+	for _, key := range []string{"CHECK_INTERVAL", "HEALTH_CHECK_PORT", "ADMIN_IDS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q doesn't mention %s", err, key)
+		}
+	}
+}
+
+// validEnv sets a token and clears the settings that could make the defaults invalid
+func validEnv(t *testing.T) {
+	t.Helper()
+
+	clearEnv(t,
+		"TELEGRAM_API_URL", "CIAN_API_URL", "DATABASE_DRIVER", "DATABASE_URL", "DATABASE_PATH", "LOG_LEVEL",
+		"LOG_FORMAT", "ACCESS_MODE", "CHECK_INTERVAL", "BOT_MODE", "WEBHOOK_URL", "WEBHOOK_PATH",
+		"TRACING_EXPORTER", "TRACING_ENDPOINT",
+	)
+	t.Setenv("TELEGRAM_TOKEN", "123:abc")
+}
+
+// validConfig returns the defaults with a token, which pass Validate
+func validConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	validEnv(t)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults don't validate: %v", err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+		want   string
+	}{
+		{"no token", func(c *config.Config) { c.TelegramToken = "" }, "TELEGRAM_TOKEN is required"},
+		{"malformed token", func(c *config.Config) { c.TelegramToken = "abc" }, "TELEGRAM_TOKEN does not look like a bot token"},
+		{"API URL without placeholders", func(c *config.Config) { c.TelegramAPIURL = "http://localhost:8081/bot" }, "TELEGRAM_API_URL"},
+		{"API URL without host", func(c *config.Config) { c.TelegramAPIURL = "localhost/bot%s/%s" }, "TELEGRAM_API_URL"},
+		{"no parser URL", func(c *config.Config) { c.CianAPIURL = "" }, "CIAN_API_URL is required"},
+		{"parser URL not http", func(c *config.Config) { c.CianAPIURL = "ftp://parser" }, "CIAN_API_URL"},
+		{"unknown driver", func(c *config.Config) { c.DatabaseDriver = "postgresql" }, "DATABASE_DRIVER"},
+		{"no database URL", func(c *config.Config) { c.DatabaseURL = "" }, "DATABASE_URL is required"},
+		{"unknown log level", func(c *config.Config) { c.LogLevel = "verbose" }, "LOG_LEVEL"},
+		{"unknown log format", func(c *config.Config) { c.LogFormat = "xml" }, "LOG_FORMAT"},
+		{"unknown access mode", func(c *config.Config) { c.AccessMode = "closed" }, "ACCESS_MODE"},
+		{"zero check interval", func(c *config.Config) { c.CheckInterval = 0 }, "CHECK_INTERVAL"},
+		{"port out of range", func(c *config.Config) { c.HealthCheckPort = 70000 }, "HEALTH_CHECK_PORT"},
+		{"zero health interval", func(c *config.Config) { c.HealthInterval = 0 }, "HEALTH_CHECK_INTERVAL"},
+		{"zero health timeout", func(c *config.Config) { c.HealthTimeout = 0 }, "HEALTH_CHECK_TIMEOUT"},
+		{"negative message limit", func(c *config.Config) { c.RateLimitMessages = -1 }, "RATE_LIMIT_MESSAGES"},
+		{"negative callback limit", func(c *config.Config) { c.RateLimitCallbacks = -1 }, "RATE_LIMIT_CALLBACKS"},
+		{"zero rate limit window", func(c *config.Config) { c.RateLimitWindow = 0 }, "RATE_LIMIT_WINDOW"},
+		{"negative refresh cooldown", func(c *config.Config) { c.RefreshCooldown = -time.Second }, "REFRESH_COOLDOWN"},
+		{"negative global refresh cooldown", func(c *config.Config) { c.RefreshGlobalCooldown = -time.Second }, "REFRESH_GLOBAL_COOLDOWN"},
+		{"negative open connections", func(c *config.Config) { c.DatabaseMaxOpenConns = -1 }, "DATABASE_MAX_OPEN_CONNS"},
+		{"negative idle connections", func(c *config.Config) { c.DatabaseMaxIdleConns = -1 }, "DATABASE_MAX_IDLE_CONNS"},
+		{"negative connection lifetime", func(c *config.Config) { c.DatabaseConnMaxLifetime = -time.Second }, "DATABASE_CONN_MAX_LIFETIME"},
+		{"negative connection idle time", func(c *config.Config) { c.DatabaseConnMaxIdleTime = -time.Second }, "DATABASE_CONN_MAX_IDLE_TIME"},
+		{"negative busy timeout", func(c *config.Config) { c.DatabaseBusyTimeout = -time.Second }, "DATABASE_BUSY_TIMEOUT"},
+		{"negative backup interval", func(c *config.Config) { c.BackupInterval = -time.Hour }, "BACKUP_INTERVAL"},
+		{"negative backup count", func(c *config.Config) { c.BackupKeep = -1 }, "BACKUP_KEEP"},
+		{"no workers", func(c *config.Config) { c.WorkerPoolSize = 0 }, "WORKER_POOL_SIZE"},
+		{"no worker queue", func(c *config.Config) { c.WorkerQueueSize = 0 }, "WORKER_QUEUE_SIZE"},
+		{"unknown bot mode", func(c *config.Config) { c.BotMode = "push" }, "BOT_MODE"},
+		{"webhook without URL", func(c *config.Config) { c.BotMode = "webhook" }, "WEBHOOK_URL is required"},
+		{"webhook path without slash", func(c *config.Config) { c.WebhookPath = "webhook" }, "WEBHOOK_PATH"},
+		{"unknown tracing exporter", func(c *config.Config) { c.TracingExporter = "jaeger" }, "TRACING_EXPORTER"},
+		{"tracing endpoint not a URL", func(c *config.Config) { c.TracingEndpoint = "collector:4318" }, "TRACING_ENDPOINT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.change(cfg)
+
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.TelegramToken = ""
+	cfg.LogLevel = "verbose"
+	cfg.WorkerPoolSize = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("invalid config passed")
+	}
+	for _, key := range []string{"TELEGRAM_TOKEN", "LOG_LEVEL", "WORKER_POOL_SIZE"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q doesn't mention %s", err, key)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// source looks settings up in the environment, then in the config file,
+// and collects every value that fails to parse
+type source struct {
+	file map[string]string
+	used map[string]bool
+	errs []error
+}
+
+func newSource(path string) (*source, error) {
+	s := &source{file: make(map[string]string), used: make(map[string]bool)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	for key, value := range values {
+		str, err := fileValueString(value)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+		s.file[strings.ToLower(key)] = str
+	}
+	return s, nil
+}
+
+// fileValueString converts a decoded YAML or TOML value to the form used in environment variables
+func fileValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string, bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			part, err := fileValueString(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", value)
+	}
+}
+
+// lookup returns the raw value of key and whether it is set
+func (s *source) lookup(key string) (string, bool) {
+	fileKey := strings.ToLower(key)
+	s.used[fileKey] = true
+
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value, true
+	}
+	if value := strings.TrimSpace(s.file[fileKey]); value != "" {
+		return value, true
+	}
+	return "", false
+}
+
+// unknownFileKeys reports config file keys that don't match any setting, usually typos
+func (s *source) unknownFileKeys() error {
+	var unknown []string
+	for key := range s.file {
+		if !s.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("config file: unknown settings %s", strings.Join(unknown, ", "))
+}
+
+func (s *source) invalid(key, value, expected string) {
+	s.errs = append(s.errs, fmt.Errorf("%s: %q is not a valid %s", key, value, expected))
+}
+
+func (s *source) getString(key, defaultValue string) string {
+	if value, ok := s.lookup(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func (s *source) getBool(key string, defaultValue bool) bool {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		s.invalid(key, value, "boolean")
+		return defaultValue
+	}
+	return parsed
+}
+
+func (s *source) getInt(key string, defaultValue int) int {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		s.invalid(key, value, "integer")
+		return defaultValue
+	}
+	return parsed
+}
+
+func (s *source) getDuration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		s.invalid(key, value, "duration such as 30s, 10m or 24h")
+		return defaultValue
+	}
+	return parsed
+}
+
+func (s *source) getInt64Slice(key string) []int64 {
+	value, _ := s.lookup(key)
+
+	var result []int64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			s.invalid(key, part, "Telegram ID")
+			continue
+		}
+		result = append(result, parsed)
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Validate checks the settings before the bot starts and reports every problem at once
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.TelegramToken == "" {
+		v.addf("TELEGRAM_TOKEN is required")
+	} else if !strings.Contains(c.TelegramToken, ":") {
+		v.addf("TELEGRAM_TOKEN does not look like a bot token from @BotFather")
+	}
+	if c.TelegramAPIURL != "" && strings.Count(c.TelegramAPIURL, "%s") != 2 {
+		v.addf("TELEGRAM_API_URL: %q must have the form http://host:port/bot%%s/%%s", c.TelegramAPIURL)
+	} else {
+		// The placeholders for the token and method are not valid URL escapes
+		v.url("TELEGRAM_API_URL", strings.ReplaceAll(c.TelegramAPIURL, "%s", "x"), false)
+	}
+	v.url("CIAN_API_URL", c.CianAPIURL, true)
+
+	v.oneOf("DATABASE_DRIVER", c.DatabaseDriver, "", "sqlite", "postgres")
+	if c.DatabaseURL == "" {
+		v.addf("DATABASE_URL is required")
+	}
+	v.oneOf("LOG_LEVEL", c.LogLevel, "debug", "info", "warn", "error")
+	v.oneOf("LOG_FORMAT", c.LogFormat, "text", "json")
+	v.oneOf("ACCESS_MODE", c.AccessMode, "open", "allowlist", "invite")
+
+	v.positive("CHECK_INTERVAL", c.CheckInterval)
+	if c.HealthCheckPort < 1 || c.HealthCheckPort > 65535 {
+		v.addf("HEALTH_CHECK_PORT: %d is not a valid port", c.HealthCheckPort)
+	}
+	v.positive("HEALTH_CHECK_INTERVAL", c.HealthInterval)
+	v.positive("HEALTH_CHECK_TIMEOUT", c.HealthTimeout)
+
+	v.atLeast("RATE_LIMIT_MESSAGES", c.RateLimitMessages, 0)
+	v.atLeast("RATE_LIMIT_CALLBACKS", c.RateLimitCallbacks, 0)
+	v.positive("RATE_LIMIT_WINDOW", c.RateLimitWindow)
+	v.notNegative("REFRESH_COOLDOWN", c.RefreshCooldown)
+	v.notNegative("REFRESH_GLOBAL_COOLDOWN", c.RefreshGlobalCooldown)
+
+	v.atLeast("DATABASE_MAX_OPEN_CONNS", c.DatabaseMaxOpenConns, 0)
+	v.atLeast("DATABASE_MAX_IDLE_CONNS", c.DatabaseMaxIdleConns, 0)
+	v.notNegative("DATABASE_CONN_MAX_LIFETIME", c.DatabaseConnMaxLifetime)
+	v.notNegative("DATABASE_CONN_MAX_IDLE_TIME", c.DatabaseConnMaxIdleTime)
+	v.notNegative("DATABASE_BUSY_TIMEOUT", c.DatabaseBusyTimeout)
+
+	v.notNegative("BACKUP_INTERVAL", c.BackupInterval)
+	v.atLeast("BACKUP_KEEP", c.BackupKeep, 0)
+
+	v.atLeast("WORKER_POOL_SIZE", c.WorkerPoolSize, 1)
+	v.atLeast("WORKER_QUEUE_SIZE", c.WorkerQueueSize, 1)
+
+	v.oneOf("BOT_MODE", c.BotMode, "polling", "webhook")
+	v.url("WEBHOOK_URL", c.WebhookURL, c.BotMode == "webhook")
+	if !strings.HasPrefix(c.WebhookPath, "/") {
+		v.addf("WEBHOOK_PATH: %q must start with /", c.WebhookPath)
+	}
+
+	v.oneOf("TRACING_EXPORTER", c.TracingExporter, "none", "stdout", "otlp")
+	v.url("TRACING_ENDPOINT", c.TracingEndpoint, false)
+
+	return errors.Join(v.errs...)
+}
+
+// validator collects validation errors
+type validator struct {
+	errs []error
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+
+	var names []string
+	for _, candidate := range allowed {
+		if candidate != "" {
+			names = append(names, candidate)
+		}
+	}
+	v.addf("%s: %q must be one of %s", key, value, strings.Join(names, ", "))
+}
+
+func (v *validator) url(key, value string, required bool) {
+	if value == "" {
+		if required {
+			v.addf("%s is required", key)
+		}
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		v.addf("%s: %q is not an http(s) URL", key, value)
+	}
+}
+
+func (v *validator) positive(key string, value time.Duration) {
+	if value <= 0 {
+		v.addf("%s: %s must be positive", key, value)
+	}
+}
+
+func (v *validator) notNegative(key string, value time.Duration) {
+	if value < 0 {
+		v.addf("%s: %s must not be negative", key, value)
+	}
+}
+
+func (v *validator) atLeast(key string, value, min int) {
+	if value < min {
+		v.addf("%s: %d must be at least %d", key, value, min)
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// watchInterval is how often the config file is checked for changes
+const watchInterval = 5 * time.Second
+
+// reloadable lists the settings a running bot picks up without a restart
+var reloadable = map[string]bool{
+	"log_level":               true,
+	"check_interval":          true,
+	"health_check_interval":   true,
+	"health_check_timeout":    true,
+	"admin_ids":               true,
+	"access_mode":             true,
+	"allowed_ids":             true,
+	"rate_limit_messages":     true,
+	"rate_limit_callbacks":    true,
+	"rate_limit_window":       true,
+	"refresh_cooldown":        true,
+	"refresh_global_cooldown": true,
+	"welcome_message":         true,
+	"help_message":            true,
+}
+
+// Change is a setting that differs between two configs; secrets are redacted in Old and New
+type Change struct {
+	Key        string
+	Old        string
+	New        string
+	Reloadable bool
+}
+
+// Diff lists the settings that differ between old and new
+func Diff(old, new *Config) []Change {
+	oldValue, newValue := reflect.ValueOf(*old), reflect.ValueOf(*new)
+	oldRedacted, newRedacted := reflect.ValueOf(*old.Redacted()), reflect.ValueOf(*new.Redacted())
+
+	var changes []Change
+	for i := 0; i < oldValue.NumField(); i++ {
+		if reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			continue
+		}
+
+		key := settingKey(oldValue.Type().Field(i))
+		changes = append(changes, Change{
+			Key:        key,
+			Old:        fmt.Sprint(oldRedacted.Field(i).Interface()),
+			New:        fmt.Sprint(newRedacted.Field(i).Interface()),
+			Reloadable: reloadable[key],
+		})
+	}
+	return changes
+}
+
+// withReloaded returns a copy of c with the reloadable settings taken from next
+func (c *Config) withReloaded(next *Config) *Config {
+	merged := *c
+	target, source := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(*next)
+	for i := 0; i < target.NumField(); i++ {
+		if reloadable[settingKey(target.Type().Field(i))] {
+			target.Field(i).Set(source.Field(i))
+		}
+	}
+	return &merged
+}
+
+func settingKey(field reflect.StructField) string {
+	return field.Tag.Get("yaml")
+}
+
+// Watcher reloads the config file on SIGHUP or when the file changes and passes valid configs
+// to apply. Invalid configs are rejected and settings that need a restart keep their running values.
+type Watcher struct {
+	path  string
+	apply func(*Config)
+
+	mu      sync.Mutex
+	current *Config
+	modTime time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewWatcher(path string, current *Config, apply func(*Config)) *Watcher {
+	return &Watcher{
+		path:    path,
+		apply:   apply,
+		current: current,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start watches for SIGHUP and file changes. SIGHUP is handled even without a config file,
+// otherwise it would terminate the bot; the reload then only re-reads the environment.
+func (w *Watcher) Start() {
+	if w.path == "" {
+		logrus.Info("No config file to watch, SIGHUP reloads the environment only")
+	} else {
+		w.modTime = fileModTime(w.path)
+		logrus.WithField("path", w.path).Info("Watching config file for changes")
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		// A nil channel never fires, so without a file only the signal triggers a reload
+		var changes <-chan time.Time
+		if w.path != "" {
+			ticker := time.NewTicker(watchInterval)
+			defer ticker.Stop()
+			changes = ticker.C
+		}
+
+		for {
+			select {
+			case <-signals:
+				logrus.Info("Received SIGHUP, reloading config")
+				w.Reload()
+			case <-changes:
+				if modTime := fileModTime(w.path); !modTime.Equal(w.modTime) {
+					w.modTime = modTime
+					logrus.Info("Config file changed, reloading config")
+					w.Reload()
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends watching
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+// Reload reads the config again and applies the changed reloadable settings
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next, err := Load(w.path)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Config reload rejected, keeping the running config")
+		return err
+	}
+
+	applied := 0
+	for _, change := range Diff(w.current, next) {
+		entry := logrus.WithFields(logrus.Fields{
+			"setting": change.Key,
+			"old":     change.Old,
+			"new":     change.New,
+		})
+		if !change.Reloadable {
+			entry.Warn("Setting changed in config but needs a restart to take effect")
+			continue
+		}
+		entry.Info("Setting reloaded")
+		applied++
+	}
+
+	if applied == 0 {
+		logrus.Info("Config reloaded, no settings to apply")
+		return nil
+	}
+
+	w.current = w.current.withReloaded(next)
+	w.apply(w.current)
+	return nil
+}
+
+// fileModTime returns the modification time of path, or zero if it can't be read
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,128 @@
+package config_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/config"
+)
+
+// newWatcher loads the config file with content and watches it, recording applied configs
+func newWatcher(t *testing.T, content string) (*config.Watcher, string, *[]*config.Config) {
+	t.Helper()
+
+	validEnv(t)
+	path := writeConfig(t, "config.yaml", content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := &[]*config.Config{}
+	watcher := config.NewWatcher(path, cfg, func(next *config.Config) {
+		*applied = append(*applied, next)
+	})
+	return watcher, path, applied
+}
+
+func rewrite(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadAppliesReloadableChange(t *testing.T) {
+	watcher, path, applied := newWatcher(t, "log_level: info\ncheck_interval: 10m\n")
+
+	rewrite(t, path, "log_level: debug\ncheck_interval: 1m\n")
+	if err := watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*applied) != 1 {
+		t.Fatalf("applied %d configs, want 1", len(*applied))
+	}
+	if cfg := (*applied)[0]; cfg.LogLevel != "debug" || cfg.CheckInterval != time.Minute {
+		t.Errorf("applied log level %q and check interval %s", cfg.LogLevel, cfg.CheckInterval)
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	content := "log_level: info\n"
+	watcher, path, applied := newWatcher(t, content)
+
+	for name, invalid := range map[string]string{
+		"invalid value":   "log_level: verbose\n",
+		"malformed value": "log_level: info\ncheck_interval: often\n",
+		"unknown setting": "log_level: info\nlog_levle: debug\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			rewrite(t, path, invalid)
+			if err := watcher.Reload(); err == nil {
+				t.Error("invalid config was accepted")
+			}
+		})
+	}
+	if len(*applied) != 0 {
+		t.Fatalf("invalid configs were applied: %+v", *applied)
+	}
+
+	// The running config is unchanged, so reloading the original file applies nothing
+	rewrite(t, path, content)
+	if err := watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*applied) != 0 {
+		t.Errorf("running config changed after rejected reloads: %+v", *applied)
+	}
+}
+
+func TestReloadKeepsSettingsThatNeedRestart(t *testing.T) {
+	watcher, path, applied := newWatcher(t, "database_url: ./bot.db\nlog_level: info\n")
+
+	rewrite(t, path, "database_url: ./other.db\nlog_level: info\n")
+	if err := watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*applied) != 0 {
+		t.Fatalf("a change that needs a restart was applied: %+v", *applied)
+	}
+
+	rewrite(t, path, "database_url: ./other.db\nlog_level: warn\n")
+	if err := watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*applied) != 1 {
+		t.Fatalf("applied %d configs, want 1", len(*applied))
+	}
+	if cfg := (*applied)[0]; cfg.LogLevel != "warn" || cfg.DatabaseURL != "./bot.db" {
+		t.Errorf("applied log level %q and database URL %q, want warn and ./bot.db", cfg.LogLevel, cfg.DatabaseURL)
+	}
+}
+
+func TestDiffRedactsSecrets(t *testing.T) {
+	old := &config.Config{TelegramToken: "123:old", LogLevel: "info"}
+	next := &config.Config{TelegramToken: "123:new", LogLevel: "debug"}
+
+	changes := config.Diff(old, next)
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	for _, change := range changes {
+		switch change.Key {
+		case "telegram_token":
+			if change.Old != "REDACTED" || change.New != "REDACTED" || change.Reloadable {
+				t.Errorf("token change = %+v", change)
+			}
+		case "log_level":
+			if change.Old != "info" || change.New != "debug" || !change.Reloadable {
+				t.Errorf("log level change = %+v", change)
+			}
+		default:
+			t.Errorf("unexpected change %+v", change)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	backupPrefix = "bot-"
+	backupSuffix = ".db"
+	// backupTimeLayout has microseconds, so backups taken within a second don't overwrite each other
+	backupTimeLayout = "20060102-150405.000000"
+)
+
+// ErrBackupUnsupported is returned when the database is not SQLite
+var ErrBackupUnsupported = errors.New("backups are only supported for SQLite")
+
+// BackupResult describes a finished backup
+type BackupResult struct {
+	Path     string
+	Size     int64
+	Duration time.Duration
+}
+
+// BackupScheduler periodically copies a live SQLite database into dir and keeps the newest backups
+type BackupScheduler struct {
+	db       *gorm.DB
+	dir      string
+	keep     int
+	interval time.Duration
+
+	// mu serializes scheduled and manually triggered backups
+	mu       sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewBackupScheduler(db *gorm.DB, dir string, keep int, interval time.Duration) *BackupScheduler {
+	return &BackupScheduler{
+		db:       db,
+		dir:      dir,
+		keep:     keep,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start runs a backup every interval; a zero interval disables scheduled backups
+func (s *BackupScheduler) Start() {
+	if s.interval <= 0 {
+		return
+	}
+	if s.db.Dialector.Name() != DriverSQLite {
+		logrus.Info("Scheduled backups are disabled: the database is not SQLite")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"interval": s.interval,
+		"dir":      s.dir,
+		"keep":     s.keep,
+	}).Info("Starting scheduled database backups")
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.Run(context.Background()); err != nil {
+					logrus.WithError(err).Error("Scheduled database backup failed")
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends scheduled backups
+func (s *BackupScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Run takes a backup now and removes backups beyond the configured number to keep
+func (s *BackupScheduler) Run(ctx context.Context) (*BackupResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := time.Now()
+	path := filepath.Join(s.dir, backupPrefix+start.Format(backupTimeLayout)+backupSuffix)
+	if err := Backup(ctx, s.db, path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	result := &BackupResult{Path: path, Size: info.Size(), Duration: time.Since(start)}
+
+	logrus.WithFields(logrus.Fields{
+		"path":        result.Path,
+		"size_bytes":  result.Size,
+		"duration_ms": result.Duration.Milliseconds(),
+	}).Info("Database backup created")
+
+	if err := rotateBackups(s.dir, s.keep); err != nil {
+		logrus.WithError(err).Warn("Failed to remove old database backups")
+	}
+
+	return result, nil
+}
+
+// Backup copies a live SQLite database to path using the SQLite online backup API,
+// which produces a consistent snapshot while the bot keeps writing
+func Backup(ctx context.Context, db *gorm.DB, path string) error {
+	if db.Dialector.Name() != DriverSQLite {
+		return ErrBackupUnsupported
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file so a crash never leaves a truncated backup under the final name
+	tmpPath := path + ".tmp"
+	_ = os.Remove(tmpPath)
+
+	if err := copySQLite(ctx, db, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func copySQLite(ctx context.Context, db *gorm.DB, destPath string) error {
+	srcDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	destDB, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return err
+	}
+	defer destDB.Close()
+
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	return destConn.Raw(func(destDriverConn interface{}) error {
+		return srcConn.Raw(func(srcDriverConn interface{}) error {
+			dest, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected destination connection type %T", destDriverConn)
+			}
+			src, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected source connection type %T", srcDriverConn)
+			}
+
+			backup, err := dest.Backup("main", src, "main")
+			if err != nil {
+				return err
+			}
+
+			// Copy everything in one step; the bot's databases are small
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// rotateBackups deletes the oldest backups in dir so that at most keep remain
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= keep {
+		return nil
+	}
+
+	// Names embed the timestamp, so lexical order is chronological
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		logrus.WithField("path", filepath.Join(dir, name)).Debug("Removed old database backup")
+	}
+	return nil
+}
@@ -1,30 +1,128 @@
 package database
 
 import (
-	"telegram_bot_service/internal/models"
+	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func Initialize(databasePath string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(databasePath), &gorm.Config{
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Options describes how to connect to the database
+type Options struct {
+	// Driver is DriverSQLite or DriverPostgres; empty means detect it from URL
+	Driver string
+	// URL is a file path for SQLite or a connection string for PostgreSQL
+	URL string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// BusyTimeout is how long SQLite waits for a lock held by another connection
+	BusyTimeout time.Duration
+}
+
+// Initialize opens the database and applies pending migrations
+func Initialize(options Options) (*gorm.DB, error) {
+	db, err := Open(options)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := MigrateUp(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range applied {
+		logrus.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info("Applied database migration")
+	}
+
+	return db, nil
+}
+
+// Open connects to the database without touching the schema
+func Open(options Options) (*gorm.DB, error) {
+	dialector, err := newDialector(options)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Auto migrate schemas
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Favorite{},
-		&models.Subscription{},
-	)
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(options.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(options.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(options.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(options.ConnMaxIdleTime)
 
 	return db, nil
 }
+
+// DetectDriver guesses the driver from a database URL
+func DetectDriver(url string) string {
+	if strings.HasPrefix(url, "postgres://") || strings.HasPrefix(url, "postgresql://") {
+		return DriverPostgres
+	}
+	return DriverSQLite
+}
+
+func newDialector(options Options) (gorm.Dialector, error) {
+	driver := options.Driver
+	if driver == "" {
+		driver = DetectDriver(options.URL)
+	}
+
+	switch driver {
+	case DriverSQLite:
+		return sqlite.Open(sqliteDSN(options.URL, options.BusyTimeout)), nil
+	case DriverPostgres:
+		return postgres.Open(options.URL), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// sqliteDSN enables WAL so readers don't block the writer, waits for locks instead of
+// failing with "database is locked", and starts write transactions with an immediate lock
+// so two transactions never deadlock upgrading read locks. Parameters already in the URL
+// come first, and the driver uses the first value of a parameter, so they take precedence.
+func sqliteDSN(path string, busyTimeout time.Duration) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=%d&_txlock=immediate",
+		path, separator, busyTimeout.Milliseconds())
+}
+
+// Ping checks the database connection without touching any tables
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,75 @@
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/database"
+	"telegram_bot_service/internal/database/dbtest"
+)
+
+func TestSQLiteURLParametersKeepPragmas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.db")
+	db, err := database.Open(database.Options{URL: path + "?_busy_timeout=1234", BusyTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	var journalMode string
+	if err := db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error; err != nil {
+		t.Fatal(err)
+	}
+	if journalMode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", journalMode)
+	}
+
+	// The value from the URL wins over BusyTimeout
+	var busyTimeout int
+	if err := db.Raw("PRAGMA busy_timeout").Scan(&busyTimeout).Error; err != nil {
+		t.Fatal(err)
+	}
+	if busyTimeout != 1234 {
+		t.Errorf("busy_timeout = %d, want 1234", busyTimeout)
+	}
+}
+
+// Open accepts the same driver names as config validation
+func TestOpenRejectsUnknownDriver(t *testing.T) {
+	for _, driver := range []string{"postgresql", "mysql"} {
+		if _, err := database.Open(database.Options{Driver: driver, URL: "postgres://localhost/bot"}); err == nil {
+			t.Errorf("driver %q was accepted", driver)
+		}
+	}
+}
+
+func TestBackupsWithinOneSecondDontOverwrite(t *testing.T) {
+	db := dbtest.SQLite(t)
+	backups := database.NewBackupScheduler(db, t.TempDir(), 10, 0)
+
+	first, err := backups.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := backups.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Path == second.Path {
+		t.Errorf("two backups share the path %s", first.Path)
+	}
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(first.Path), "bot-*.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Errorf("backups on disk = %v, want 2", matches)
+	}
+}
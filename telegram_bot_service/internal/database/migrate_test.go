@@ -0,0 +1,57 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"telegram_bot_service/internal/database"
+	"telegram_bot_service/internal/database/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+
+		states, err := database.MigrationStatus(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, state := range states {
+			if !state.Applied {
+				t.Errorf("migration %d_%s is not applied", state.Version, state.Name)
+			}
+		}
+
+		// Reverting the later migrations must keep the indexes of stored_listings, which SQLite
+		// loses when a column is dropped
+		if _, err := database.MigrateDown(ctx, db, 3); err != nil {
+			t.Fatalf("migrate down: %v", err)
+		}
+		for _, index := range []string{"idx_stored_listings_fingerprint", "idx_stored_listings_canonical_id", "idx_stored_listings_first_seen_at"} {
+			if !db.Migrator().HasIndex("stored_listings", index) {
+				t.Errorf("index %s is missing after migrate down", index)
+			}
+		}
+
+		reverted, err := database.MigrateDown(ctx, db, len(states))
+		if err != nil {
+			t.Fatalf("migrate down: %v", err)
+		}
+		if len(reverted) != len(states)-3 {
+			t.Errorf("reverted %d migrations, want %d", len(reverted), len(states)-3)
+		}
+		if db.Migrator().HasTable("users") {
+			t.Error("users table left after reverting all migrations")
+		}
+
+		applied, err := database.MigrateUp(ctx, db)
+		if err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		if len(applied) != len(states) {
+			t.Errorf("applied %d migrations, want %d", len(applied), len(states))
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations is the ordered history of the schema. Never edit an applied migration;
+// add a new one instead. Migrations use their own copies of the models so that later
+// changes to internal/models don't alter what an old migration does.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		// Matches what AutoMigrate created before versioned migrations, so existing
+		// databases pass through it unchanged
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v1User{}, &v1Favorite{}, &v1Subscription{}, &v1InviteCode{}, &v1AccessGrant{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v1AccessGrant{}, &v1InviteCode{}, &v1Subscription{}, &v1Favorite{}, &v1User{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "unique_favorites",
+		// Keeps the oldest of duplicate favorites created by concurrent AddToFavorites calls
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(`DELETE FROM favorites WHERE id NOT IN (
+				SELECT MIN(id) FROM favorites GROUP BY user_id, listing_id
+			)`).Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE UNIQUE INDEX idx_favorites_user_listing ON favorites (user_id, listing_id)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP INDEX idx_favorites_user_listing").Error
+		},
+	},
+	{
+		Version: 3,
+		Name:    "listing_history",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v3StoredListing{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v3StoredListing{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "listing_fingerprints",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"Fingerprint", "PriceBand", "PhotoHash", "CanonicalID"} {
+				if err := tx.Migrator().AddColumn(&v4StoredListing{}, column); err != nil {
+					return err
+				}
+			}
+			for _, index := range []string{"Fingerprint", "CanonicalID"} {
+				if err := tx.Migrator().CreateIndex(&v4StoredListing{}, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, index := range []string{"Fingerprint", "CanonicalID"} {
+				if err := tx.Migrator().DropIndex(&v4StoredListing{}, index); err != nil {
+					return err
+				}
+			}
+			for _, column := range []string{"Fingerprint", "PriceBand", "PhotoHash", "CanonicalID"} {
+				if err := tx.Migrator().DropColumn(&v4StoredListing{}, column); err != nil {
+					return err
+				}
+			}
+			return restoreIndexes(tx, &v3StoredListing{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "user_settings",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&v5User{}, "Settings")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&v5User{}, "Settings"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx, &v1User{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "stored_listing_district",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&v6StoredListing{}, "District")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&v6StoredListing{}, "District"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx, &v3StoredListing{}, &v4StoredListing{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "subscription_names",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&v7Subscription{}, "Name")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&v7Subscription{}, "Name"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx, &v1Subscription{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "user_blocked_at",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&v8User{}, "BlockedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&v8User{}, "BlockedAt"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx, &v1User{})
+		},
+	},
+}
+
+// restoreIndexes creates the missing indexes declared on the given versions of a table. On
+// SQLite GORM drops a column by rebuilding the table, which loses its indexes, so a Down that
+// drops a column calls this with the versions that declared the remaining indexes; GORM only
+// sees indexes declared on the model itself, not on embedded versions.
+func restoreIndexes(tx *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		for name := range stmt.Schema.ParseIndexes() {
+			if tx.Migrator().HasIndex(model, name) {
+				continue
+			}
+			if err := tx.Migrator().CreateIndex(model, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type v1User struct {
+	ID        int64 `gorm:"primaryKey"`
+	Username  string
+	FirstName string
+	LastName  string
+	Language  string
+	IsActive  bool `gorm:"default:true"`
+	IsAdmin   bool `gorm:"default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1Favorite struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    int64
+	ListingID string
+	Title     string
+	Price     string
+	URL       string
+	Note      string
+	CreatedAt time.Time
+	User      v1User `gorm:"foreignKey:UserID"`
+}
+
+func (v1Favorite) TableName() string { return "favorites" }
+
+type v1Subscription struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    int64
+	IsActive  bool `gorm:"default:true"`
+	Settings  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	User      v1User         `gorm:"foreignKey:UserID"`
+}
+
+func (v1Subscription) TableName() string { return "subscriptions" }
+
+type v1InviteCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"uniqueIndex"`
+	CreatedBy int64
+	MaxUses   int
+	Uses      int
+	CreatedAt time.Time
+}
+
+func (v1InviteCode) TableName() string { return "invite_codes" }
+
+type v1AccessGrant struct {
+	UserID     int64 `gorm:"primaryKey"`
+	GrantedBy  int64
+	InviteCode string
+	CreatedAt  time.Time
+}
+
+func (v1AccessGrant) TableName() string { return "access_grants" }
+
+type v3StoredListing struct {
+	ID          string `gorm:"primaryKey"`
+	Title       string
+	Price       string
+	PriceValue  int
+	Address     string
+	URL         string
+	Area        string
+	Rooms       string
+	Floor       string
+	Metro       string
+	FirstSeenAt time.Time `gorm:"index"`
+	LastSeenAt  time.Time
+}
+
+func (v3StoredListing) TableName() string { return "stored_listings" }
+
+type v4StoredListing struct {
+	v3StoredListing
+	Fingerprint string `gorm:"index"`
+	PriceBand   int
+	PhotoHash   int64
+	CanonicalID string `gorm:"index"`
+}
+
+func (v4StoredListing) TableName() string { return "stored_listings" }
+
+type v5User struct {
+	v1User
+	Settings string
+}
+
+func (v5User) TableName() string { return "users" }
+
+type v6StoredListing struct {
+	v4StoredListing
+	District string
+}
+
+func (v6StoredListing) TableName() string { return "stored_listings" }
+
+type v7Subscription struct {
+	v1Subscription
+	Name string
+}
+
+func (v7Subscription) TableName() string { return "subscriptions" }
+
+type v8User struct {
+	v5User
+	BlockedAt *time.Time
+}
+
+func (v8User) TableName() string { return "users" }
@@ -0,0 +1,218 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Check verifies a single dependency; it should respect ctx cancellation
+type Check func(ctx context.Context) error
+
+// Status is the cached result of the latest checks of a dependency
+type Status struct {
+	Name        string        `json:"name"`
+	Critical    bool          `json:"critical"`
+	Healthy     bool          `json:"healthy"`
+	Latency     time.Duration `json:"-"`
+	LatencyMs   int64         `json:"latency_ms"`
+	LastChecked time.Time     `json:"last_checked"`
+	LastSuccess time.Time     `json:"last_success"`
+	LastError   string        `json:"last_error,omitempty"`
+}
+
+type dependency struct {
+	name     string
+	critical bool
+	check    Check
+}
+
+// Prober runs dependency checks in the background so health endpoints never block on them
+type Prober struct {
+	mu           sync.RWMutex
+	interval     time.Duration
+	timeout      time.Duration
+	dependencies []dependency
+	statuses     map[string]*Status
+
+	// reset restarts the wait for the next round after the interval changed
+	reset    chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewProber(interval, timeout time.Duration) *Prober {
+	return &Prober{
+		interval: interval,
+		timeout:  timeout,
+		statuses: make(map[string]*Status),
+		reset:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Register adds a dependency; failing critical dependencies make the service not ready
+func (p *Prober) Register(name string, critical bool, check Check) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dependencies = append(p.dependencies, dependency{name: name, critical: critical, check: check})
+	p.statuses[name] = &Status{Name: name, Critical: critical}
+}
+
+// Start probes all dependencies immediately and then every interval
+func (p *Prober) Start() {
+	logrus.WithField("interval", p.Interval()).Info("Starting health prober")
+
+	go func() {
+		for {
+			p.probeAll()
+			if !p.wait() {
+				return
+			}
+		}
+	}()
+}
+
+// wait sleeps until the next round, starting over when the interval changes; it returns false once stopped
+func (p *Prober) wait() bool {
+	for {
+		timer := time.NewTimer(p.Interval())
+		select {
+		case <-timer.C:
+			return true
+		case <-p.reset:
+			timer.Stop()
+		case <-p.stop:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// Interval returns how often dependencies are probed
+func (p *Prober) Interval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.interval
+}
+
+// SetInterval changes the probing interval and timeout of a running prober
+func (p *Prober) SetInterval(interval, timeout time.Duration) {
+	p.mu.Lock()
+	p.interval = interval
+	p.timeout = timeout
+	p.mu.Unlock()
+
+	select {
+	case p.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Stop ends background probing
+func (p *Prober) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// Statuses returns a snapshot of all dependency statuses ordered by name
+func (p *Prober) Statuses() []Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(p.statuses))
+	for _, status := range p.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Ready reports whether all critical dependencies passed their latest check
+func (p *Prober) Ready() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, status := range p.statuses {
+		if status.Critical && !status.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Prober) probeAll() {
+	p.mu.RLock()
+	dependencies := append([]dependency(nil), p.dependencies...)
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, dep := range dependencies {
+		wg.Add(1)
+		go func(dep dependency) {
+			defer wg.Done()
+			p.probe(dep)
+		}(dep)
+	}
+	wg.Wait()
+}
+
+func (p *Prober) probe(dep dependency) {
+	p.mu.RLock()
+	timeout := p.timeout
+	p.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runCheck(ctx, dep.check)
+	latency := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := p.statuses[dep.name]
+	wasHealthy := status.Healthy
+	firstCheck := status.LastChecked.IsZero()
+
+	status.Latency = latency
+	status.LatencyMs = latency.Milliseconds()
+	status.LastChecked = start
+	status.Healthy = err == nil
+	if err == nil {
+		status.LastSuccess = start
+		status.LastError = ""
+	} else {
+		status.LastError = err.Error()
+	}
+
+	// Log only transitions so a flapping dependency doesn't flood the log on every probe
+	if err != nil && (wasHealthy || firstCheck) {
+		logrus.WithError(err).WithField("dependency", dep.name).Warn("Health check failed")
+	} else if err == nil && !wasHealthy && !firstCheck {
+		logrus.WithField("dependency", dep.name).Info("Health check recovered")
+	}
+}
+
+// runCheck enforces the timeout even for checks that ignore ctx
+func runCheck(ctx context.Context, check Check) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
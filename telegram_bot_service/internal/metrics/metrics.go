@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cian_bot"
+
+var (
+	// CommandsTotal counts received commands by name
+	CommandsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Number of received bot commands.",
+	}, []string{"command"})
+
+	// CallbacksTotal counts callback queries by action
+	CallbacksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "callbacks_total",
+		Help:      "Number of received callback queries.",
+	}, []string{"action"})
+
+	// HandlerDuration observes how long an update takes to handle
+	HandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent handling an update.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+
+	// ParserRequestDuration observes CIAN parser API latency by endpoint
+	ParserRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "parser_request_duration_seconds",
+		Help:      "Latency of requests to the CIAN parser API.",
+		// Forced refreshes scrape CIAN and can take minutes
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"endpoint"})
+
+	// ParserErrorsTotal counts failed CIAN parser API requests by endpoint
+	ParserErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parser_errors_total",
+		Help:      "Number of failed requests to the CIAN parser API.",
+	}, []string{"endpoint"})
+
+	// ListingsFetchedTotal counts listings received from the parser
+	ListingsFetchedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "listings_fetched_total",
+		Help:      "Number of listings received from the CIAN parser API.",
+	})
+
+	// NewListingsTotal counts listings seen for the first time
+	NewListingsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "new_listings_total",
+		Help:      "Number of newly detected listings.",
+	})
+
+	// DuplicateListingsTotal counts new listings collapsed into another listing of the same flat
+	DuplicateListingsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_listings_total",
+		Help:      "Number of new listings recognized as duplicates or reposts of another listing.",
+	})
+
+	// NotificationsSentTotal counts delivered notifications by kind
+	NotificationsSentTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Number of notifications delivered to users.",
+	}, []string{"kind"})
+
+	// NotificationsFailedTotal counts undelivered notifications by kind and reason
+	NotificationsFailedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_failed_total",
+		Help:      "Number of notifications that could not be delivered.",
+	}, []string{"kind", "reason"})
+
+	// OutboundQueueDepth is the number of messages waiting to be sent by background deliveries
+	OutboundQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "outbound_queue_depth",
+		Help:      "Number of outgoing messages waiting to be sent.",
+	})
+)
+
+// RegisterGaugeFunc exposes a gauge whose value is computed on every scrape
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
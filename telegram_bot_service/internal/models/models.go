@@ -14,15 +14,25 @@ type User struct {
 	LastName  string    `json:"last_name"`
 	Language  string    `json:"language"`
 	IsActive  bool      `gorm:"default:true" json:"is_active"`
+	IsAdmin   bool      `gorm:"default:false" json:"is_admin"`
+	Settings  string    `json:"settings"` // JSON string with personal preferences such as ranking weights
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// BlockedAt is when a message to the user failed because they blocked the bot; cleared when they write again
+	BlockedAt *time.Time `json:"blocked_at,omitempty"`
+}
+
+// Reachable reports whether the bot may message the user: not deactivated by an admin and not blocked
+func (u *User) Reachable() bool {
+	return u.IsActive && u.BlockedAt == nil
 }
 
 // Favorite represents a user's favorite listing
 type Favorite struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    int64     `json:"user_id"`
-	ListingID string    `json:"listing_id"`
+	UserID    int64     `gorm:"uniqueIndex:idx_favorites_user_listing" json:"user_id"`
+	ListingID string    `gorm:"uniqueIndex:idx_favorites_user_listing" json:"listing_id"`
 	Title     string    `json:"title"`
 	Price     string    `json:"price"`
 	URL       string    `json:"url"`
@@ -35,6 +45,7 @@ type Favorite struct {
 type Subscription struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	UserID    int64          `json:"user_id"`
+	Name      string         `json:"name"` // empty for the subscription to all listings
 	IsActive  bool           `gorm:"default:true" json:"is_active"`
 	Settings  string         `json:"settings"` // JSON string with search settings
 	CreatedAt time.Time      `json:"created_at"`
@@ -43,6 +54,24 @@ type Subscription struct {
 	User      User           `gorm:"foreignKey:UserID" json:"user"`
 }
 
+// InviteCode represents an invitation that grants access to the bot in invite mode
+type InviteCode struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"uniqueIndex" json:"code"`
+	CreatedBy int64     `json:"created_by"`
+	MaxUses   int       `json:"max_uses"`
+	Uses      int       `json:"uses"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccessGrant represents a user approved by an admin or through an invite code
+type AccessGrant struct {
+	UserID     int64     `gorm:"primaryKey" json:"user_id"`
+	GrantedBy  int64     `json:"granted_by"`
+	InviteCode string    `json:"invite_code"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // Listing represents a property listing from CIAN
 type Listing struct {
 	ID          string   `json:"id"`
@@ -57,5 +86,31 @@ type Listing struct {
 	Rooms       string   `json:"rooms"`
 	Floor       string   `json:"floor"`
 	Metro       string   `json:"metro"`
+	District    string   `json:"district"`
+	AuthorType  string   `json:"author_type"`
+	Commission  int      `json:"commissions"` // percent of the monthly rent
 	PublishedAt string   `json:"published_at"`
 }
+
+// StoredListing is a listing seen by the notifier; the history detects new listings and price changes
+type StoredListing struct {
+	ID         string `gorm:"primaryKey" json:"id"`
+	Title      string `json:"title"`
+	Price      string `json:"price"`
+	PriceValue int    `json:"price_value"`
+	Address    string `json:"address"`
+	URL        string `json:"url"`
+	Area       string `json:"area"`
+	Rooms      string `json:"rooms"`
+	Floor      string `json:"floor"`
+	Metro      string `json:"metro"`
+	District   string `json:"district"`
+	// Fingerprint, PriceBand and PhotoHash identify the flat across reposts, see services.Fingerprint
+	Fingerprint string `gorm:"index" json:"fingerprint"`
+	PriceBand   int    `json:"price_band"`
+	PhotoHash   int64  `json:"photo_hash"`
+	// CanonicalID is the ID of the first listing of the same flat; empty for that listing itself
+	CanonicalID string    `gorm:"index" json:"canonical_id"`
+	FirstSeenAt time.Time `gorm:"index" json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
@@ -0,0 +1,137 @@
+// Package parsertest provides a fake CIAN parser API serving fixture listings
+package parsertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"telegram_bot_service/internal/models"
+)
+
+// Server is an httptest server implementing the parser's /listings, /settings and /health
+type Server struct {
+	URL string
+
+	httpServer *httptest.Server
+
+	mu        sync.Mutex
+	listings  []models.Listing
+	settings  map[string]interface{}
+	healthy   bool
+	refreshes int
+}
+
+// NewServer starts a fake parser serving listings; call Close when done
+func NewServer(listings []models.Listing) *Server {
+	s := &Server{
+		listings: listings,
+		settings: map[string]interface{}{"min_price": 30000, "max_price": 80000},
+		healthy:  true,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/listings", s.handleListings)
+	mux.HandleFunc("/settings", s.handleSettings)
+	mux.HandleFunc("/health", s.handleHealth)
+
+	s.httpServer = httptest.NewServer(mux)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// SetListings replaces the listings returned from now on
+func (s *Server) SetListings(listings []models.Listing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listings = listings
+}
+
+// SetHealthy controls whether /health reports success
+func (s *Server) SetHealthy(healthy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.healthy = healthy
+}
+
+// Settings returns the current search settings
+func (s *Server) Settings() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := make(map[string]interface{}, len(s.settings))
+	for key, value := range s.settings {
+		settings[key] = value
+	}
+	return settings
+}
+
+// Refreshes returns how many times listings were requested with refresh=true
+func (s *Server) Refreshes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.refreshes
+}
+
+func (s *Server) handleListings(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if r.URL.Query().Get("refresh") == "true" {
+		s.refreshes++
+	}
+	listings := s.listings
+	s.mu.Unlock()
+
+	if listings == nil {
+		listings = []models.Listing{}
+	}
+	writeJSON(w, http.StatusOK, listings)
+}
+
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Settings())
+	case http.MethodPut:
+		var update map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		for key, value := range update {
+			s.settings[key] = value
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]string{"status": "success"})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	healthy := s.healthy
+	s.mu.Unlock()
+
+	if !healthy {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
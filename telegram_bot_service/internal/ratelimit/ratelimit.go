@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a sliding-window rate limiter keyed by an int64 ID (user or chat)
+type Limiter struct {
+	mu          sync.Mutex
+	limit       int
+	window      time.Duration
+	hits        map[int64][]time.Time
+	lastCleanup time.Time
+}
+
+// New creates a limiter allowing limit events per window for each key.
+// A non-positive limit or window disables limiting.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:       limit,
+		window:      window,
+		hits:        make(map[int64][]time.Time),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// When the event is rejected, retryAfter tells how long until the next slot frees up.
+func (l *Limiter) Allow(key int64) (allowed bool, retryAfter time.Duration) {
+	if l == nil || l.limit <= 0 || l.window <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.cleanup(now)
+
+	hits := l.prune(l.hits[key], now)
+	if len(hits) >= l.limit {
+		l.hits[key] = hits
+		return false, hits[0].Add(l.window).Sub(now)
+	}
+
+	l.hits[key] = append(hits, now)
+	return true, 0
+}
+
+// SetLimit changes the limit and window; events already recorded count against the new limit
+func (l *Limiter) SetLimit(limit int, window time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.window = window
+}
+
+// Reset forgets all recorded events for key
+func (l *Limiter) Reset(key int64) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.hits, key)
+}
+
+// prune drops events that fell out of the window
+func (l *Limiter) prune(hits []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
+
+// cleanup removes idle keys so the map does not grow with every user ever seen
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < l.window {
+		return
+	}
+
+	for key, hits := range l.hits {
+		if len(l.prune(hits, now)) == 0 {
+			delete(l.hits, key)
+		}
+	}
+	l.lastCleanup = now
+}
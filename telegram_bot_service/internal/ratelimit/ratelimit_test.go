@@ -0,0 +1,121 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/ratelimit"
+)
+
+func TestAllowBurstUpToLimit(t *testing.T) {
+	limiter := ratelimit.New(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow(42); !allowed {
+			t.Fatalf("event %d of 3 was rejected", i+1)
+		}
+	}
+
+	allowed, retryAfter := limiter.Allow(42)
+	if allowed {
+		t.Fatal("event over the limit was allowed")
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Errorf("retryAfter = %s, want within the window", retryAfter)
+	}
+}
+
+func TestAllowAgainAfterWindow(t *testing.T) {
+	const window = 50 * time.Millisecond
+	limiter := ratelimit.New(1, window)
+
+	if allowed, _ := limiter.Allow(42); !allowed {
+		t.Fatal("first event was rejected")
+	}
+	allowed, retryAfter := limiter.Allow(42)
+	if allowed {
+		t.Fatal("second event within the window was allowed")
+	}
+
+	time.Sleep(retryAfter + 10*time.Millisecond)
+	if allowed, _ := limiter.Allow(42); !allowed {
+		t.Error("event after the window was rejected")
+	}
+}
+
+// Rejected events don't take a slot, so retrying early doesn't push the wait further
+func TestRejectedEventsAreNotRecorded(t *testing.T) {
+	const window = 100 * time.Millisecond
+	limiter := ratelimit.New(1, window)
+
+	limiter.Allow(42)
+	time.Sleep(window / 2)
+	allowed, retryAfter := limiter.Allow(42)
+	if allowed {
+		t.Fatal("second event within the window was allowed")
+	}
+
+	time.Sleep(retryAfter + 10*time.Millisecond)
+	if allowed, _ := limiter.Allow(42); !allowed {
+		t.Error("the rejected event extended the wait")
+	}
+}
+
+func TestKeysAreLimitedSeparately(t *testing.T) {
+	limiter := ratelimit.New(1, time.Minute)
+
+	if allowed, _ := limiter.Allow(42); !allowed {
+		t.Fatal("first event of 42 was rejected")
+	}
+	if allowed, _ := limiter.Allow(42); allowed {
+		t.Fatal("second event of 42 was allowed")
+	}
+	if allowed, _ := limiter.Allow(43); !allowed {
+		t.Error("43 was limited by the events of 42")
+	}
+}
+
+func TestReset(t *testing.T) {
+	limiter := ratelimit.New(1, time.Minute)
+
+	limiter.Allow(42)
+	limiter.Reset(42)
+	if allowed, _ := limiter.Allow(42); !allowed {
+		t.Error("event after Reset was rejected")
+	}
+}
+
+func TestSetLimitKeepsRecordedEvents(t *testing.T) {
+	limiter := ratelimit.New(3, time.Minute)
+
+	limiter.Allow(42)
+	limiter.Allow(42)
+	limiter.SetLimit(2, time.Minute)
+	if allowed, _ := limiter.Allow(42); allowed {
+		t.Error("events before SetLimit didn't count against the new limit")
+	}
+
+	limiter.SetLimit(3, time.Minute)
+	if allowed, _ := limiter.Allow(42); !allowed {
+		t.Error("raised limit wasn't applied")
+	}
+}
+
+func TestDisabledLimiterAllowsEverything(t *testing.T) {
+	var nilLimiter *ratelimit.Limiter
+	limiters := map[string]*ratelimit.Limiter{
+		"nil":         nilLimiter,
+		"zero limit":  ratelimit.New(0, time.Minute),
+		"zero window": ratelimit.New(1, 0),
+	}
+
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				if allowed, retryAfter := limiter.Allow(42); !allowed || retryAfter != 0 {
+					t.Fatalf("event %d: allowed = %v, retryAfter = %s", i+1, allowed, retryAfter)
+				}
+			}
+		})
+	}
+}
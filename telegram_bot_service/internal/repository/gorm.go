@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"telegram_bot_service/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) GetUser(ctx context.Context, userID int64) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	// Only the profile columns, so a concurrent deactivation or settings change is not overwritten
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"username":   user.Username,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"is_admin":   user.IsAdmin,
+	}).Error
+}
+
+func (r *gormUserRepository) SetUserActive(ctx context.Context, userID int64, active bool) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("is_active", active).Error
+}
+
+func (r *gormUserRepository) SetUserBlockedAt(ctx context.Context, userID int64, blockedAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("blocked_at", blockedAt).Error
+}
+
+func (r *gormUserRepository) UpdateUserSettings(ctx context.Context, userID int64, settings string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("settings", settings).Error
+}
+
+func (r *gormUserRepository) ListUsers(ctx context.Context, limit int) ([]models.User, error) {
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUserRepository) ListActiveUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	if err := r.db.WithContext(ctx).Where("is_active = ? AND blocked_at IS NULL", true).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUserRepository) CountUsers(ctx context.Context) (total int64, active int64, err error) {
+	if err = r.db.WithContext(ctx).Model(&models.User{}).Count(&total).Error; err != nil {
+		return 0, 0, err
+	}
+	if err = r.db.WithContext(ctx).Model(&models.User{}).Where("is_active = ? AND blocked_at IS NULL", true).Count(&active).Error; err != nil {
+		return 0, 0, err
+	}
+	return total, active, nil
+}
+
+type gormFavoriteRepository struct {
+	db *gorm.DB
+}
+
+func NewGormFavoriteRepository(db *gorm.DB) FavoriteRepository {
+	return &gormFavoriteRepository{db: db}
+}
+
+func (r *gormFavoriteRepository) GetFavorite(ctx context.Context, userID int64, listingID string) (*models.Favorite, error) {
+	var favorite models.Favorite
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND listing_id = ?", userID, listingID).First(&favorite).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &favorite, nil
+}
+
+func (r *gormFavoriteRepository) ListFavorites(ctx context.Context, userID int64) ([]models.Favorite, error) {
+	var favorites []models.Favorite
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&favorites).Error; err != nil {
+		return nil, err
+	}
+	return favorites, nil
+}
+
+func (r *gormFavoriteRepository) CreateFavorite(ctx context.Context, favorite *models.Favorite) (bool, error) {
+	// The unique index on (user_id, listing_id) turns a concurrent duplicate into a no-op
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(favorite)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *gormFavoriteRepository) UpdateFavoriteNote(ctx context.Context, userID int64, listingID, note string) error {
+	return r.db.WithContext(ctx).Model(&models.Favorite{}).Where("user_id = ? AND listing_id = ?", userID, listingID).Update("note", note).Error
+}
+
+func (r *gormFavoriteRepository) DeleteFavorite(ctx context.Context, userID int64, listingID string) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND listing_id = ?", userID, listingID).Delete(&models.Favorite{}).Error
+}
+
+func (r *gormFavoriteRepository) CountFavorites(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Favorite{}).Count(&count).Error
+	return count, err
+}
+
+type gormSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &gormSubscriptionRepository{db: db}
+}
+
+// activeSubscriptions selects active subscriptions of reachable users
+func (r *gormSubscriptionRepository) activeSubscriptions(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.Subscription{}).
+		Joins("JOIN users ON users.id = subscriptions.user_id").
+		Where("subscriptions.is_active = ? AND users.is_active = ? AND users.blocked_at IS NULL", true, true)
+}
+
+func (r *gormSubscriptionRepository) CountActiveSubscriptions(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.activeSubscriptions(ctx).Count(&count).Error
+	return count, err
+}
+
+func (r *gormSubscriptionRepository) ListActiveSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.activeSubscriptions(ctx).Order("subscriptions.id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *gormSubscriptionRepository) ListUserSubscriptions(ctx context.Context, userID int64) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *gormSubscriptionRepository) CreateSubscription(ctx context.Context, subscription *models.Subscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *gormSubscriptionRepository) UpdateSubscription(ctx context.Context, subscription *models.Subscription) error {
+	result := r.db.WithContext(ctx).Model(&models.Subscription{}).
+		Where("id = ? AND user_id = ?", subscription.ID, subscription.UserID).
+		Updates(map[string]interface{}{
+			"name":      subscription.Name,
+			"is_active": subscription.IsActive,
+			"settings":  subscription.Settings,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSubscriptionRepository) DeleteSubscription(ctx context.Context, userID int64, id uint) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.Subscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// listingsQueryBatch keeps IN lists well below the bind variable limits of the databases
+const listingsQueryBatch = 500
+
+type gormListingRepository struct {
+	db *gorm.DB
+}
+
+func NewGormListingRepository(db *gorm.DB) ListingRepository {
+	return &gormListingRepository{db: db}
+}
+
+func (r *gormListingRepository) GetListings(ctx context.Context, ids []string) (map[string]models.StoredListing, error) {
+	result := make(map[string]models.StoredListing, len(ids))
+	for start := 0; start < len(ids); start += listingsQueryBatch {
+		end := min(start+listingsQueryBatch, len(ids))
+
+		var listings []models.StoredListing
+		if err := r.db.WithContext(ctx).Where("id IN ?", ids[start:end]).Find(&listings).Error; err != nil {
+			return nil, err
+		}
+		for _, listing := range listings {
+			result[listing.ID] = listing
+		}
+	}
+	return result, nil
+}
+
+func (r *gormListingRepository) SaveListings(ctx context.Context, listings []models.StoredListing) error {
+	if len(listings) == 0 {
+		return nil
+	}
+	updates := clause.AssignmentColumns([]string{
+		"title", "price", "price_value", "address", "url", "area", "rooms", "floor", "metro",
+		"district", "fingerprint", "price_band", "last_seen_at",
+	})
+	// The history only computes these for unknown listings, so a zero value keeps the stored one
+	updates = append(updates,
+		clause.Assignment{
+			Column: clause.Column{Name: "photo_hash"},
+			Value:  gorm.Expr("CASE WHEN excluded.photo_hash <> 0 THEN excluded.photo_hash ELSE stored_listings.photo_hash END"),
+		},
+		clause.Assignment{
+			Column: clause.Column{Name: "canonical_id"},
+			Value:  gorm.Expr("CASE WHEN excluded.canonical_id <> '' THEN excluded.canonical_id ELSE stored_listings.canonical_id END"),
+		},
+	)
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: updates,
+	}).CreateInBatches(listings, 100).Error
+}
+
+func (r *gormListingRepository) FindByFingerprints(ctx context.Context, fingerprints []string) ([]models.StoredListing, error) {
+	var result []models.StoredListing
+	for start := 0; start < len(fingerprints); start += listingsQueryBatch {
+		end := min(start+listingsQueryBatch, len(fingerprints))
+
+		var listings []models.StoredListing
+		if err := r.db.WithContext(ctx).Where("fingerprint IN ?", fingerprints[start:end]).Find(&listings).Error; err != nil {
+			return nil, err
+		}
+		result = append(result, listings...)
+	}
+	return result, nil
+}
+
+func (r *gormListingRepository) ListWithPhotoHash(ctx context.Context, since time.Time) ([]models.StoredListing, error) {
+	var listings []models.StoredListing
+	err := r.db.WithContext(ctx).Where("photo_hash <> 0 AND last_seen_at >= ?", since).Find(&listings).Error
+	return listings, err
+}
+
+func (r *gormListingRepository) ListSeenSince(ctx context.Context, since time.Time) ([]models.StoredListing, error) {
+	var listings []models.StoredListing
+	err := r.db.WithContext(ctx).Where("last_seen_at >= ?", since).Find(&listings).Error
+	return listings, err
+}
+
+func (r *gormListingRepository) CountListings(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.StoredListing{}).Count(&count).Error
+	return count, err
+}
+
+type gormAccessRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAccessRepository(db *gorm.DB) AccessRepository {
+	return &gormAccessRepository{db: db}
+}
+
+func (r *gormAccessRepository) HasGrant(ctx context.Context, userID int64) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.AccessGrant{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *gormAccessRepository) CreateGrant(ctx context.Context, grant *models.AccessGrant) error {
+	return r.db.WithContext(ctx).Where(models.AccessGrant{UserID: grant.UserID}).FirstOrCreate(grant).Error
+}
+
+func (r *gormAccessRepository) DeleteGrant(ctx context.Context, userID int64) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.AccessGrant{}).Error
+}
+
+func (r *gormAccessRepository) CreateInviteCode(ctx context.Context, invite *models.InviteCode) error {
+	return r.db.WithContext(ctx).Create(invite).Error
+}
+
+func (r *gormAccessRepository) ListActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error) {
+	var invites []models.InviteCode
+	if err := r.db.WithContext(ctx).Where("uses < max_uses").Order("created_at DESC").Find(&invites).Error; err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (r *gormAccessRepository) DeleteInviteCode(ctx context.Context, code string) error {
+	return r.db.WithContext(ctx).Where("code = ?", code).Delete(&models.InviteCode{}).Error
+}
+
+func (r *gormAccessRepository) RedeemInviteCode(ctx context.Context, code string, userID int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.AccessGrant{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		// Conditional increment keeps concurrent redemptions within the limit
+		result := tx.Model(&models.InviteCode{}).
+			Where("code = ? AND uses < max_uses", code).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidInviteCode
+		}
+
+		var invite models.InviteCode
+		if err := tx.Where("code = ?", code).First(&invite).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.AccessGrant{
+			UserID:     userID,
+			GrantedBy:  invite.CreatedBy,
+			InviteCode: code,
+		}).Error
+	})
+}
+
+// notFound maps GORM's missing-record error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
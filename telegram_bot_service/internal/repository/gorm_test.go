@@ -0,0 +1,218 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/database/dbtest"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestGormUsers(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		users := repository.NewGormUserRepository(db)
+
+		if _, err := users.GetUser(ctx, 1); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetUser of a missing user: %v, want ErrNotFound", err)
+		}
+
+		for _, id := range []int64{1, 2} {
+			if err := users.CreateUser(ctx, &models.User{ID: id, Username: "user", IsActive: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := users.SetUserActive(ctx, 2, false); err != nil {
+			t.Fatal(err)
+		}
+
+		active, err := users.ListActiveUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(active) != 1 || active[0].ID != 1 {
+			t.Errorf("active users = %+v, want only user 1", active)
+		}
+
+		total, activeCount, err := users.CountUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 2 || activeCount != 1 {
+			t.Errorf("CountUsers = %d, %d, want 2, 1", total, activeCount)
+		}
+
+		// A profile update from a stale read keeps the state written in the meantime
+		stale, err := users.GetUser(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := users.SetUserActive(ctx, 1, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := users.UpdateUserSettings(ctx, 1, `{"weights":{}}`); err != nil {
+			t.Fatal(err)
+		}
+		stale.Username = "renamed"
+		if err := users.UpdateUser(ctx, stale); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := users.GetUser(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Username != "renamed" || updated.IsActive || updated.Settings != `{"weights":{}}` {
+			t.Errorf("after UpdateUser: %+v", updated)
+		}
+	})
+}
+
+func TestGormFavorites(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		favorites := repository.NewGormFavoriteRepository(db)
+		if err := repository.NewGormUserRepository(db).CreateUser(ctx, &models.User{ID: 1, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		created, err := favorites.CreateFavorite(ctx, &models.Favorite{UserID: 1, ListingID: "100", Title: "2-комн."})
+		if err != nil || !created {
+			t.Fatalf("CreateFavorite = %v, %v, want true", created, err)
+		}
+		created, err = favorites.CreateFavorite(ctx, &models.Favorite{UserID: 1, ListingID: "100", Title: "2-комн."})
+		if err != nil || created {
+			t.Fatalf("CreateFavorite of a duplicate = %v, %v, want false", created, err)
+		}
+
+		if err := favorites.UpdateFavoriteNote(ctx, 1, "100", "позвонить"); err != nil {
+			t.Fatal(err)
+		}
+		favorite, err := favorites.GetFavorite(ctx, 1, "100")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if favorite.Note != "позвонить" {
+			t.Errorf("note = %q", favorite.Note)
+		}
+
+		if err := favorites.DeleteFavorite(ctx, 1, "100"); err != nil {
+			t.Fatal(err)
+		}
+		if count, err := favorites.CountFavorites(ctx); err != nil || count != 0 {
+			t.Errorf("CountFavorites after delete = %d, %v", count, err)
+		}
+	})
+}
+
+func TestGormActiveSubscriptionsSkipUnreachableUsers(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		users := repository.NewGormUserRepository(db)
+		subscriptions := repository.NewGormSubscriptionRepository(db)
+
+		for _, id := range []int64{1, 2, 3} {
+			if err := users.CreateUser(ctx, &models.User{ID: id, IsActive: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := subscriptions.CreateSubscription(ctx, &models.Subscription{UserID: id, IsActive: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := users.SetUserActive(ctx, 2, false); err != nil {
+			t.Fatal(err)
+		}
+		blockedAt := time.Now()
+		if err := users.SetUserBlockedAt(ctx, 3, &blockedAt); err != nil {
+			t.Fatal(err)
+		}
+
+		active, err := subscriptions.ListActiveSubscriptions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(active) != 1 || active[0].UserID != 1 {
+			t.Errorf("active subscriptions = %+v, want only user 1's", active)
+		}
+		if count, err := subscriptions.CountActiveSubscriptions(ctx); err != nil || count != 1 {
+			t.Errorf("CountActiveSubscriptions = %d, %v, want 1", count, err)
+		}
+
+		// Unblocking brings the subscription back
+		if err := users.SetUserBlockedAt(ctx, 3, nil); err != nil {
+			t.Fatal(err)
+		}
+		if count, err := subscriptions.CountActiveSubscriptions(ctx); err != nil || count != 2 {
+			t.Errorf("CountActiveSubscriptions after unblocking = %d, %v, want 2", count, err)
+		}
+	})
+}
+
+func TestGormRedeemInviteCode(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		access := repository.NewGormAccessRepository(db)
+
+		if err := access.CreateInviteCode(ctx, &models.InviteCode{Code: "abc", CreatedBy: 1, MaxUses: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := access.RedeemInviteCode(ctx, "abc", 2); err != nil {
+			t.Fatal(err)
+		}
+		// Redeeming again with access already granted doesn't use up the code
+		if err := access.RedeemInviteCode(ctx, "abc", 2); err != nil {
+			t.Fatal(err)
+		}
+		if err := access.RedeemInviteCode(ctx, "abc", 3); !errors.Is(err, repository.ErrInvalidInviteCode) {
+			t.Errorf("redeeming an exhausted code: %v, want ErrInvalidInviteCode", err)
+		}
+
+		if granted, err := access.HasGrant(ctx, 2); err != nil || !granted {
+			t.Errorf("HasGrant(2) = %v, %v", granted, err)
+		}
+		if granted, err := access.HasGrant(ctx, 3); err != nil || granted {
+			t.Errorf("HasGrant(3) = %v, %v", granted, err)
+		}
+	})
+}
+
+func TestGormSaveListingsUpdatesKnownListings(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		listings := repository.NewGormListingRepository(db)
+		firstSeen := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		lastSeen := firstSeen.Add(time.Hour)
+
+		save := func(listing models.StoredListing) models.StoredListing {
+			t.Helper()
+			if err := listings.SaveListings(ctx, []models.StoredListing{listing}); err != nil {
+				t.Fatal(err)
+			}
+			stored, err := listings.GetListings(ctx, []string{listing.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return stored[listing.ID]
+		}
+
+		save(models.StoredListing{ID: "1", PriceValue: 50000, FirstSeenAt: firstSeen, LastSeenAt: firstSeen})
+
+		stored := save(models.StoredListing{ID: "1", PriceValue: 55000, PhotoHash: 7, CanonicalID: "0", FirstSeenAt: lastSeen, LastSeenAt: lastSeen})
+		if stored.PriceValue != 55000 || stored.PhotoHash != 7 || stored.CanonicalID != "0" {
+			t.Errorf("after an update: %+v", stored)
+		}
+		if !stored.FirstSeenAt.Equal(firstSeen) || !stored.LastSeenAt.Equal(lastSeen) {
+			t.Errorf("first seen %s, last seen %s, want %s and %s", stored.FirstSeenAt, stored.LastSeenAt, firstSeen, lastSeen)
+		}
+
+		// The history doesn't recompute these for known listings
+		stored = save(models.StoredListing{ID: "1", PriceValue: 55000, FirstSeenAt: lastSeen, LastSeenAt: lastSeen})
+		if stored.PhotoHash != 7 || stored.CanonicalID != "0" {
+			t.Errorf("an update without photo hash and canonical ID cleared them: %+v", stored)
+		}
+	})
+}
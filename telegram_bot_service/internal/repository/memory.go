@@ -0,0 +1,509 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"telegram_bot_service/internal/models"
+	"time"
+)
+
+// The in-memory repositories keep everything in maps guarded by a mutex. They behave like the
+// GORM ones for the bot's purposes and let handlers run without a database, e.g. in tests.
+
+type MemoryUserRepository struct {
+	mu    sync.Mutex
+	users map[int64]models.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[int64]models.User)}
+}
+
+func (r *MemoryUserRepository) GetUser(ctx context.Context, userID int64) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return nil
+	}
+	stored.Username = user.Username
+	stored.FirstName = user.FirstName
+	stored.LastName = user.LastName
+	stored.IsAdmin = user.IsAdmin
+	stored.UpdatedAt = time.Now()
+	r.users[user.ID] = stored
+	return nil
+}
+
+func (r *MemoryUserRepository) SetUserActive(ctx context.Context, userID int64, active bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.IsActive = active
+		r.users[userID] = user
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) SetUserBlockedAt(ctx context.Context, userID int64, blockedAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.BlockedAt = blockedAt
+		r.users[userID] = user
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateUserSettings(ctx context.Context, userID int64, settings string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.Settings = settings
+		r.users[userID] = user
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) ListUsers(ctx context.Context, limit int) ([]models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := make([]models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *MemoryUserRepository) ListActiveUsers(ctx context.Context) ([]models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []models.User
+	for _, user := range r.users {
+		if user.Reachable() {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+func (r *MemoryUserRepository) CountUsers(ctx context.Context) (total int64, active int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		total++
+		if user.Reachable() {
+			active++
+		}
+	}
+	return total, active, nil
+}
+
+type favoriteKey struct {
+	userID    int64
+	listingID string
+}
+
+type MemoryFavoriteRepository struct {
+	mu        sync.Mutex
+	nextID    uint
+	favorites map[favoriteKey]models.Favorite
+}
+
+func NewMemoryFavoriteRepository() *MemoryFavoriteRepository {
+	return &MemoryFavoriteRepository{favorites: make(map[favoriteKey]models.Favorite)}
+}
+
+func (r *MemoryFavoriteRepository) GetFavorite(ctx context.Context, userID int64, listingID string) (*models.Favorite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	favorite, ok := r.favorites[favoriteKey{userID, listingID}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &favorite, nil
+}
+
+func (r *MemoryFavoriteRepository) ListFavorites(ctx context.Context, userID int64) ([]models.Favorite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var favorites []models.Favorite
+	for key, favorite := range r.favorites {
+		if key.userID == userID {
+			favorites = append(favorites, favorite)
+		}
+	}
+	// IDs grow with insertion, which is a stable stand-in for created_at
+	sort.Slice(favorites, func(i, j int) bool {
+		return favorites[i].ID > favorites[j].ID
+	})
+	return favorites, nil
+}
+
+func (r *MemoryFavoriteRepository) CreateFavorite(ctx context.Context, favorite *models.Favorite) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey{favorite.UserID, favorite.ListingID}
+	if _, ok := r.favorites[key]; ok {
+		return false, nil
+	}
+
+	r.nextID++
+	favorite.ID = r.nextID
+	favorite.CreatedAt = time.Now()
+	r.favorites[key] = *favorite
+	return true, nil
+}
+
+func (r *MemoryFavoriteRepository) UpdateFavoriteNote(ctx context.Context, userID int64, listingID, note string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey{userID, listingID}
+	if favorite, ok := r.favorites[key]; ok {
+		favorite.Note = note
+		r.favorites[key] = favorite
+	}
+	return nil
+}
+
+func (r *MemoryFavoriteRepository) DeleteFavorite(ctx context.Context, userID int64, listingID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.favorites, favoriteKey{userID, listingID})
+	return nil
+}
+
+func (r *MemoryFavoriteRepository) CountFavorites(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int64(len(r.favorites)), nil
+}
+
+type MemorySubscriptionRepository struct {
+	mu            sync.Mutex
+	subscriptions []models.Subscription
+	nextID        uint
+	// users decides which subscriptions are active like the join with the users table does
+	users *MemoryUserRepository
+}
+
+func NewMemorySubscriptionRepository(users *MemoryUserRepository) *MemorySubscriptionRepository {
+	return &MemorySubscriptionRepository{users: users}
+}
+
+func (r *MemorySubscriptionRepository) CountActiveSubscriptions(ctx context.Context) (int64, error) {
+	subscriptions, err := r.ListActiveSubscriptions(ctx)
+	return int64(len(subscriptions)), err
+}
+
+func (r *MemorySubscriptionRepository) ListActiveSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var subscriptions []models.Subscription
+	for _, subscription := range r.subscriptions {
+		if subscription.IsActive && r.userReachable(ctx, subscription.UserID) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *MemorySubscriptionRepository) userReachable(ctx context.Context, userID int64) bool {
+	user, err := r.users.GetUser(ctx, userID)
+	return err == nil && user.Reachable()
+}
+
+func (r *MemorySubscriptionRepository) ListUserSubscriptions(ctx context.Context, userID int64) ([]models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var subscriptions []models.Subscription
+	for _, subscription := range r.subscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *MemorySubscriptionRepository) CreateSubscription(ctx context.Context, subscription *models.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.nextID++
+	subscription.ID = r.nextID
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+	r.subscriptions = append(r.subscriptions, *subscription)
+	return nil
+}
+
+func (r *MemorySubscriptionRepository) UpdateSubscription(ctx context.Context, subscription *models.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.subscriptions {
+		if r.subscriptions[i].ID == subscription.ID && r.subscriptions[i].UserID == subscription.UserID {
+			r.subscriptions[i].Name = subscription.Name
+			r.subscriptions[i].IsActive = subscription.IsActive
+			r.subscriptions[i].Settings = subscription.Settings
+			r.subscriptions[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemorySubscriptionRepository) DeleteSubscription(ctx context.Context, userID int64, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.subscriptions {
+		if r.subscriptions[i].ID == id && r.subscriptions[i].UserID == userID {
+			r.subscriptions = append(r.subscriptions[:i], r.subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type MemoryListingRepository struct {
+	mu       sync.Mutex
+	listings map[string]models.StoredListing
+}
+
+func NewMemoryListingRepository() *MemoryListingRepository {
+	return &MemoryListingRepository{listings: make(map[string]models.StoredListing)}
+}
+
+func (r *MemoryListingRepository) GetListings(ctx context.Context, ids []string) (map[string]models.StoredListing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make(map[string]models.StoredListing, len(ids))
+	for _, id := range ids {
+		if listing, ok := r.listings[id]; ok {
+			result[id] = listing
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryListingRepository) SaveListings(ctx context.Context, listings []models.StoredListing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, listing := range listings {
+		if existing, ok := r.listings[listing.ID]; ok {
+			listing.FirstSeenAt = existing.FirstSeenAt
+			if listing.PhotoHash == 0 {
+				listing.PhotoHash = existing.PhotoHash
+			}
+			if listing.CanonicalID == "" {
+				listing.CanonicalID = existing.CanonicalID
+			}
+		}
+		r.listings[listing.ID] = listing
+	}
+	return nil
+}
+
+func (r *MemoryListingRepository) FindByFingerprints(ctx context.Context, fingerprints []string) ([]models.StoredListing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[string]bool, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		wanted[fingerprint] = true
+	}
+
+	var listings []models.StoredListing
+	for _, listing := range r.listings {
+		if listing.Fingerprint != "" && wanted[listing.Fingerprint] {
+			listings = append(listings, listing)
+		}
+	}
+	return listings, nil
+}
+
+func (r *MemoryListingRepository) ListWithPhotoHash(ctx context.Context, since time.Time) ([]models.StoredListing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var listings []models.StoredListing
+	for _, listing := range r.listings {
+		if listing.PhotoHash != 0 && !listing.LastSeenAt.Before(since) {
+			listings = append(listings, listing)
+		}
+	}
+	return listings, nil
+}
+
+func (r *MemoryListingRepository) ListSeenSince(ctx context.Context, since time.Time) ([]models.StoredListing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var listings []models.StoredListing
+	for _, listing := range r.listings {
+		if !listing.LastSeenAt.Before(since) {
+			listings = append(listings, listing)
+		}
+	}
+	return listings, nil
+}
+
+func (r *MemoryListingRepository) CountListings(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int64(len(r.listings)), nil
+}
+
+type MemoryAccessRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	grants  map[int64]models.AccessGrant
+	invites map[string]models.InviteCode
+}
+
+func NewMemoryAccessRepository() *MemoryAccessRepository {
+	return &MemoryAccessRepository{
+		grants:  make(map[int64]models.AccessGrant),
+		invites: make(map[string]models.InviteCode),
+	}
+}
+
+func (r *MemoryAccessRepository) HasGrant(ctx context.Context, userID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.grants[userID]
+	return ok, nil
+}
+
+func (r *MemoryAccessRepository) CreateGrant(ctx context.Context, grant *models.AccessGrant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.grants[grant.UserID]; ok {
+		*grant = existing
+		return nil
+	}
+	grant.CreatedAt = time.Now()
+	r.grants[grant.UserID] = *grant
+	return nil
+}
+
+func (r *MemoryAccessRepository) DeleteGrant(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.grants, userID)
+	return nil
+}
+
+func (r *MemoryAccessRepository) CreateInviteCode(ctx context.Context, invite *models.InviteCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	invite.ID = r.nextID
+	invite.CreatedAt = time.Now()
+	r.invites[invite.Code] = *invite
+	return nil
+}
+
+func (r *MemoryAccessRepository) ListActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var invites []models.InviteCode
+	for _, invite := range r.invites {
+		if invite.Uses < invite.MaxUses {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].ID > invites[j].ID
+	})
+	return invites, nil
+}
+
+func (r *MemoryAccessRepository) DeleteInviteCode(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.invites, code)
+	return nil
+}
+
+func (r *MemoryAccessRepository) RedeemInviteCode(ctx context.Context, code string, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.grants[userID]; ok {
+		return nil
+	}
+
+	invite, ok := r.invites[code]
+	if !ok || invite.Uses >= invite.MaxUses {
+		return ErrInvalidInviteCode
+	}
+	invite.Uses++
+	r.invites[code] = invite
+
+	r.grants[userID] = models.AccessGrant{
+		UserID:     userID,
+		GrantedBy:  invite.CreatedBy,
+		InviteCode: code,
+		CreatedAt:  time.Now(),
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"telegram_bot_service/internal/models"
+	"time"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// ErrInvalidInviteCode is returned when an invite code does not exist or is used up
+var ErrInvalidInviteCode = errors.New("invalid or exhausted invite code")
+
+// UserRepository stores Telegram users
+type UserRepository interface {
+	GetUser(ctx context.Context, userID int64) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	// UpdateUser saves the profile from Telegram: username, names and IsAdmin; the other
+	// columns are changed only by their own methods
+	UpdateUser(ctx context.Context, user *models.User) error
+	SetUserActive(ctx context.Context, userID int64, active bool) error
+	// SetUserBlockedAt records when the user blocked the bot; nil clears it
+	SetUserBlockedAt(ctx context.Context, userID int64, blockedAt *time.Time) error
+	UpdateUserSettings(ctx context.Context, userID int64, settings string) error
+	// ListUsers returns users newest first; a non-positive limit returns all of them
+	ListUsers(ctx context.Context, limit int) ([]models.User, error)
+	// ListActiveUsers and the active count of CountUsers include only reachable users, see models.User.Reachable
+	ListActiveUsers(ctx context.Context) ([]models.User, error)
+	CountUsers(ctx context.Context) (total int64, active int64, err error)
+}
+
+// FavoriteRepository stores users' favorite listings
+type FavoriteRepository interface {
+	GetFavorite(ctx context.Context, userID int64, listingID string) (*models.Favorite, error)
+	// ListFavorites returns a user's favorites newest first
+	ListFavorites(ctx context.Context, userID int64) ([]models.Favorite, error)
+	// CreateFavorite stores favorite unless the user already saved that listing
+	CreateFavorite(ctx context.Context, favorite *models.Favorite) (created bool, err error)
+	UpdateFavoriteNote(ctx context.Context, userID int64, listingID, note string) error
+	DeleteFavorite(ctx context.Context, userID int64, listingID string) error
+	CountFavorites(ctx context.Context) (int64, error)
+}
+
+// SubscriptionRepository stores notification subscriptions
+type SubscriptionRepository interface {
+	// CountActiveSubscriptions and ListActiveSubscriptions skip subscriptions of unreachable users
+	CountActiveSubscriptions(ctx context.Context) (int64, error)
+	ListActiveSubscriptions(ctx context.Context) ([]models.Subscription, error)
+	ListUserSubscriptions(ctx context.Context, userID int64) ([]models.Subscription, error)
+	CreateSubscription(ctx context.Context, subscription *models.Subscription) error
+	// UpdateSubscription saves the name, state and settings of a user's subscription
+	UpdateSubscription(ctx context.Context, subscription *models.Subscription) error
+	DeleteSubscription(ctx context.Context, userID int64, id uint) error
+}
+
+// ListingRepository stores the history of listings seen by the notifier
+type ListingRepository interface {
+	// GetListings returns the stored listings with the given IDs keyed by ID
+	GetListings(ctx context.Context, ids []string) (map[string]models.StoredListing, error)
+	// SaveListings inserts new listings and updates known ones, keeping their FirstSeenAt.
+	// PhotoHash and CanonicalID are updated only when set, a zero value keeps the stored one.
+	SaveListings(ctx context.Context, listings []models.StoredListing) error
+	CountListings(ctx context.Context) (int64, error)
+	// FindByFingerprints returns stored listings with any of the fingerprint keys
+	FindByFingerprints(ctx context.Context, fingerprints []string) ([]models.StoredListing, error)
+	// ListWithPhotoHash returns listings with a photo hash seen since the given time
+	ListWithPhotoHash(ctx context.Context, since time.Time) ([]models.StoredListing, error)
+	// ListSeenSince returns listings seen since the given time
+	ListSeenSince(ctx context.Context, since time.Time) ([]models.StoredListing, error)
+}
+
+// AccessRepository stores access grants and invite codes
+type AccessRepository interface {
+	HasGrant(ctx context.Context, userID int64) (bool, error)
+	// CreateGrant approves a user; approving an approved user is a no-op
+	CreateGrant(ctx context.Context, grant *models.AccessGrant) error
+	DeleteGrant(ctx context.Context, userID int64) error
+	CreateInviteCode(ctx context.Context, invite *models.InviteCode) error
+	// ListActiveInviteCodes returns invite codes with uses left, newest first
+	ListActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error)
+	DeleteInviteCode(ctx context.Context, code string) error
+	// RedeemInviteCode atomically consumes one use of code and grants access to userID.
+	// Users that already have access keep their grant and don't consume a use.
+	RedeemInviteCode(ctx context.Context, code string, userID int64) error
+}
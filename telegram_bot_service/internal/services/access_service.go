@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AccessModeOpen      = "open"
+	AccessModeAllowlist = "allowlist"
+	AccessModeInvite    = "invite"
+)
+
+// ErrInvalidInviteCode is returned when an invite code does not exist or is used up
+var ErrInvalidInviteCode = repository.ErrInvalidInviteCode
+
+type AccessService struct {
+	access repository.AccessRepository
+
+	mu      sync.RWMutex
+	mode    string
+	allowed map[int64]bool
+}
+
+// NewAccessService creates an access service; adminIDs always have access
+func NewAccessService(access repository.AccessRepository, mode string, allowedIDs, adminIDs []int64) *AccessService {
+	s := &AccessService{access: access}
+	s.SetPolicy(mode, allowedIDs, adminIDs)
+	return s
+}
+
+// SetPolicy replaces the access mode and the lists of users allowed without a grant
+func (s *AccessService) SetPolicy(mode string, allowedIDs, adminIDs []int64) {
+	switch mode {
+	case AccessModeOpen, AccessModeAllowlist, AccessModeInvite:
+	default:
+		logrus.WithField("mode", mode).Warn("Unknown access mode, falling back to allowlist")
+		mode = AccessModeAllowlist
+	}
+
+	allowed := make(map[int64]bool, len(allowedIDs)+len(adminIDs))
+	for _, id := range allowedIDs {
+		allowed[id] = true
+	}
+	for _, id := range adminIDs {
+		allowed[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+	s.allowed = allowed
+}
+
+// Mode returns the configured access mode
+func (s *AccessService) Mode() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mode
+}
+
+// HasAccess checks if a user is allowed to use the bot
+func (s *AccessService) HasAccess(ctx context.Context, userID int64) (bool, error) {
+	s.mu.RLock()
+	open := s.mode == AccessModeOpen || s.allowed[userID]
+	s.mu.RUnlock()
+
+	if open {
+		return true, nil
+	}
+	return s.access.HasGrant(ctx, userID)
+}
+
+// GrantAccess approves a user manually
+func (s *AccessService) GrantAccess(ctx context.Context, userID, grantedBy int64) error {
+	return s.access.CreateGrant(ctx, &models.AccessGrant{UserID: userID, GrantedBy: grantedBy})
+}
+
+// RevokeAccess removes a user's approval
+func (s *AccessService) RevokeAccess(ctx context.Context, userID int64) error {
+	return s.access.DeleteGrant(ctx, userID)
+}
+
+// CreateInviteCode generates a new invite code usable maxUses times
+func (s *AccessService) CreateInviteCode(ctx context.Context, createdBy int64, maxUses int) (*models.InviteCode, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	invite := &models.InviteCode{
+		Code:      hex.EncodeToString(buf),
+		CreatedBy: createdBy,
+		MaxUses:   maxUses,
+	}
+
+	if err := s.access.CreateInviteCode(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// GetActiveInviteCodes gets invite codes that still have uses left
+func (s *AccessService) GetActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error) {
+	return s.access.ListActiveInviteCodes(ctx)
+}
+
+// DeleteInviteCode invalidates an invite code
+func (s *AccessService) DeleteInviteCode(ctx context.Context, code string) error {
+	return s.access.DeleteInviteCode(ctx, code)
+}
+
+// RedeemInviteCode consumes one use of an invite code and approves the user
+func (s *AccessService) RedeemInviteCode(ctx context.Context, code string, userID int64) error {
+	return s.access.RedeemInviteCode(ctx, code, userID)
+}
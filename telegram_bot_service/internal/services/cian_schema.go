@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"telegram_bot_service/internal/models"
+)
+
+// requiredListingFields are the fields the bot can't show or save a listing without
+var requiredListingFields = map[string]bool{"id": true, "title": true, "url": true}
+
+// SchemaReport describes how the parser's listings match models.Listing
+type SchemaReport struct {
+	Listings int
+	// Errors are listings the bot can't use: missing required fields or values of the wrong type
+	Errors []string
+	// MissingFields counts listings without each optional field
+	MissingFields map[string]int
+	// UnknownFields are fields the bot ignores
+	UnknownFields []string
+}
+
+// maxSchemaErrors bounds the report for a parser that returns thousands of broken listings
+const maxSchemaErrors = 20
+
+// CheckSchema fetches listings and validates their JSON against models.Listing
+func (s *CianService) CheckSchema(ctx context.Context) (*SchemaReport, error) {
+	resp, err := s.do(ctx, http.MethodGet, fmt.Sprintf("%s/listings", s.baseURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return ValidateListingsSchema(body)
+}
+
+// ValidateListingsSchema checks a /listings response body field by field
+func ValidateListingsSchema(body []byte) (*SchemaReport, error) {
+	var listings []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &listings); err != nil {
+		return nil, fmt.Errorf("response is not a JSON array of objects: %w", err)
+	}
+
+	fields := listingFields()
+	report := &SchemaReport{Listings: len(listings), MissingFields: make(map[string]int)}
+	unknown := make(map[string]bool)
+	errorCount := 0
+	addError := func(format string, args ...interface{}) {
+		errorCount++
+		if errorCount <= maxSchemaErrors {
+			report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+		}
+	}
+
+	for i, listing := range listings {
+		for name, kind := range fields {
+			raw, ok := listing[name]
+			if !ok || string(raw) == "null" {
+				if requiredListingFields[name] {
+					addError("listing %d: missing required field %q", i, name)
+				} else {
+					report.MissingFields[name]++
+				}
+				continue
+			}
+			if got := jsonKind(raw); got != kind {
+				addError("listing %d: field %q is %s, expected %s", i, name, got, kind)
+			}
+		}
+		for name := range listing {
+			if _, ok := fields[name]; !ok {
+				unknown[name] = true
+			}
+		}
+	}
+
+	if errorCount > maxSchemaErrors {
+		report.Errors = append(report.Errors, fmt.Sprintf("... and %d more errors", errorCount-maxSchemaErrors))
+	}
+	for name := range unknown {
+		report.UnknownFields = append(report.UnknownFields, name)
+	}
+	sort.Strings(report.UnknownFields)
+
+	return report, nil
+}
+
+// listingFields maps the JSON fields of models.Listing to their expected JSON kinds
+func listingFields() map[string]string {
+	fields := make(map[string]string)
+	listingType := reflect.TypeOf(models.Listing{})
+	for i := 0; i < listingType.NumField(); i++ {
+		field := listingType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch field.Type.Kind() {
+		case reflect.String:
+			fields[name] = "string"
+		case reflect.Int, reflect.Int64, reflect.Float64:
+			fields[name] = "number"
+		case reflect.Slice:
+			fields[name] = "array"
+		}
+	}
+	return fields
+}
+
+// jsonKind names the JSON type of a raw value
+func jsonKind(raw json.RawMessage) string {
+	switch raw[0] {
+	case '"':
+		return "string"
+	case '[':
+		return "array"
+	case '{':
+		return "object"
+	case 't', 'f':
+		return "boolean"
+	default:
+		return "number"
+	}
+}
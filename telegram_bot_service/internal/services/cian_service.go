@@ -2,14 +2,19 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/metrics"
 	"telegram_bot_service/internal/models"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CianService struct {
@@ -22,20 +27,32 @@ func NewCianService(baseURL string) *CianService {
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: time.Second * 30,
+			// Creates a client span per call and sends the traceparent header to the parser
+			Transport: otelhttp.NewTransport(http.DefaultTransport,
+				otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+					return "cian " + r.Method + " " + r.URL.Path
+				}),
+				// Background health probes would otherwise start a new trace every interval
+				otelhttp.WithFilter(func(r *http.Request) bool {
+					return trace.SpanContextFromContext(r.Context()).IsValid()
+				}),
+			),
 		},
 	}
 }
 
 // GetListings fetches listings from CIAN API
-func (s *CianService) GetListings(forceRefresh bool) ([]models.Listing, error) {
+func (s *CianService) GetListings(ctx context.Context, forceRefresh bool) (listings []models.Listing, err error) {
+	defer observeRequest(ctx, "listings", time.Now(), &err)
+
 	url := fmt.Sprintf("%s/listings", s.baseURL)
 	if forceRefresh {
 		url += "?refresh=true"
 	}
 
-	resp, err := s.httpClient.Get(url)
+	resp, err := s.do(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to fetch listings")
+		logging.FromContext(ctx).WithError(err).Error("Failed to fetch listings")
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -49,22 +66,24 @@ func (s *CianService) GetListings(forceRefresh bool) ([]models.Listing, error) {
 		return nil, err
 	}
 
-	var listings []models.Listing
 	if err := json.Unmarshal(body, &listings); err != nil {
 		return nil, err
 	}
 
-	logrus.WithField("count", len(listings)).Debug("Fetched listings from CIAN API")
+	metrics.ListingsFetchedTotal.Add(float64(len(listings)))
+	logging.FromContext(ctx).WithField("count", len(listings)).Debug("Fetched listings from CIAN API")
 	return listings, nil
 }
 
 // GetSettings fetches current search settings
-func (s *CianService) GetSettings() (map[string]interface{}, error) {
+func (s *CianService) GetSettings(ctx context.Context) (settings map[string]interface{}, err error) {
+	defer observeRequest(ctx, "settings", time.Now(), &err)
+
 	url := fmt.Sprintf("%s/settings", s.baseURL)
 
-	resp, err := s.httpClient.Get(url)
+	resp, err := s.do(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to fetch settings")
+		logging.FromContext(ctx).WithError(err).Error("Failed to fetch settings")
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -78,17 +97,18 @@ func (s *CianService) GetSettings() (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	var settings map[string]interface{}
 	if err := json.Unmarshal(body, &settings); err != nil {
 		return nil, err
 	}
 
-	logrus.Debug("Fetched settings from CIAN API")
+	logging.FromContext(ctx).Debug("Fetched settings from CIAN API")
 	return settings, nil
 }
 
 // UpdateSettings updates search settings
-func (s *CianService) UpdateSettings(settings map[string]interface{}) error {
+func (s *CianService) UpdateSettings(ctx context.Context, settings map[string]interface{}) (err error) {
+	defer observeRequest(ctx, "update_settings", time.Now(), &err)
+
 	url := fmt.Sprintf("%s/settings", s.baseURL)
 
 	jsonData, err := json.Marshal(settings)
@@ -96,15 +116,9 @@ func (s *CianService) UpdateSettings(settings map[string]interface{}) error {
 		return err
 	}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+	resp, err := s.do(ctx, http.MethodPut, url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to update settings")
+		logging.FromContext(ctx).WithError(err).Error("Failed to update settings")
 		return err
 	}
 	defer resp.Body.Close()
@@ -113,15 +127,17 @@ func (s *CianService) UpdateSettings(settings map[string]interface{}) error {
 		return fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
-	logrus.Debug("Updated settings via CIAN API")
+	logging.FromContext(ctx).Debug("Updated settings via CIAN API")
 	return nil
 }
 
 // HealthCheck checks if CIAN API is healthy
-func (s *CianService) HealthCheck() error {
+func (s *CianService) HealthCheck(ctx context.Context) (err error) {
+	defer observeRequest(ctx, "health", time.Now(), &err)
+
 	url := fmt.Sprintf("%s/health", s.baseURL)
 
-	resp, err := s.httpClient.Get(url)
+	resp, err := s.do(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
@@ -133,3 +149,34 @@ func (s *CianService) HealthCheck() error {
 
 	return nil
 }
+
+// do sends a request to the CIAN API, propagating the request ID from ctx
+func (s *CianService) do(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID)
+	}
+
+	return s.httpClient.Do(req)
+}
+
+// observeRequest records latency and outcome of a CIAN API call
+func observeRequest(ctx context.Context, endpoint string, start time.Time, err *error) {
+	latency := time.Since(start)
+	metrics.ParserRequestDuration.WithLabelValues(endpoint).Observe(latency.Seconds())
+	if *err != nil {
+		metrics.ParserErrorsTotal.WithLabelValues(endpoint).Inc()
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"endpoint":   endpoint,
+		"latency_ms": latency.Milliseconds(),
+	}).Debug("CIAN API request finished")
+}
@@ -1,35 +1,35 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"telegram_bot_service/internal/models"
-
-	"gorm.io/gorm"
+	"telegram_bot_service/internal/repository"
 )
 
 type FavoriteService struct {
-	db *gorm.DB
+	favorites repository.FavoriteRepository
 }
 
-func NewFavoriteService(db *gorm.DB) *FavoriteService {
-	return &FavoriteService{db: db}
+func NewFavoriteService(favorites repository.FavoriteRepository) *FavoriteService {
+	return &FavoriteService{favorites: favorites}
 }
 
 // AddToFavorites adds a listing to user's favorites
-func (s *FavoriteService) AddToFavorites(userID int64, listing *models.Listing, note string) (*models.Favorite, error) {
+func (s *FavoriteService) AddToFavorites(ctx context.Context, userID int64, listing *models.Listing, note string) (*models.Favorite, error) {
 	// Check if already in favorites
-	var existing models.Favorite
-	result := s.db.Where("user_id = ? AND listing_id = ?", userID, listing.ID).First(&existing)
-	if result.Error == nil {
+	existing, err := s.favorites.GetFavorite(ctx, userID, listing.ID)
+	if err == nil {
 		// Already exists, update note if provided
 		if note != "" {
-			existing.Note = note
-			if err := s.db.Save(&existing).Error; err != nil {
+			if err := s.favorites.UpdateFavoriteNote(ctx, userID, listing.ID, note); err != nil {
 				return nil, err
 			}
+			existing.Note = note
 		}
-		return &existing, nil
-	} else if result.Error != gorm.ErrRecordNotFound {
-		return nil, result.Error
+		return existing, nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
 	}
 
 	// Create new favorite
@@ -42,44 +42,45 @@ func (s *FavoriteService) AddToFavorites(userID int64, listing *models.Listing,
 		Note:      note,
 	}
 
-	if err := s.db.Create(favorite).Error; err != nil {
+	created, err := s.favorites.CreateFavorite(ctx, favorite)
+	if err != nil {
 		return nil, err
 	}
+	if !created {
+		// A concurrent call added the same listing since the check above
+		return s.favorites.GetFavorite(ctx, userID, listing.ID)
+	}
 
 	return favorite, nil
 }
 
 // RemoveFromFavorites removes a listing from user's favorites
-func (s *FavoriteService) RemoveFromFavorites(userID int64, listingID string) error {
-	return s.db.Where("user_id = ? AND listing_id = ?", userID, listingID).Delete(&models.Favorite{}).Error
+func (s *FavoriteService) RemoveFromFavorites(ctx context.Context, userID int64, listingID string) error {
+	return s.favorites.DeleteFavorite(ctx, userID, listingID)
 }
 
 // GetUserFavorites gets all favorites for a user
-func (s *FavoriteService) GetUserFavorites(userID int64) ([]models.Favorite, error) {
-	var favorites []models.Favorite
-	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&favorites).Error; err != nil {
-		return nil, err
-	}
-	return favorites, nil
+func (s *FavoriteService) GetUserFavorites(ctx context.Context, userID int64) ([]models.Favorite, error) {
+	return s.favorites.ListFavorites(ctx, userID)
 }
 
 // GetFavorite gets a specific favorite
-func (s *FavoriteService) GetFavorite(userID int64, listingID string) (*models.Favorite, error) {
-	var favorite models.Favorite
-	if err := s.db.Where("user_id = ? AND listing_id = ?", userID, listingID).First(&favorite).Error; err != nil {
-		return nil, err
-	}
-	return &favorite, nil
+func (s *FavoriteService) GetFavorite(ctx context.Context, userID int64, listingID string) (*models.Favorite, error) {
+	return s.favorites.GetFavorite(ctx, userID, listingID)
 }
 
 // IsFavorite checks if a listing is in user's favorites
-func (s *FavoriteService) IsFavorite(userID int64, listingID string) bool {
-	var count int64
-	s.db.Model(&models.Favorite{}).Where("user_id = ? AND listing_id = ?", userID, listingID).Count(&count)
-	return count > 0
+func (s *FavoriteService) IsFavorite(ctx context.Context, userID int64, listingID string) bool {
+	_, err := s.favorites.GetFavorite(ctx, userID, listingID)
+	return err == nil
 }
 
 // UpdateFavoriteNote updates the note for a favorite
-func (s *FavoriteService) UpdateFavoriteNote(userID int64, listingID, note string) error {
-	return s.db.Model(&models.Favorite{}).Where("user_id = ? AND listing_id = ?", userID, listingID).Update("note", note).Error
+func (s *FavoriteService) UpdateFavoriteNote(ctx context.Context, userID int64, listingID, note string) error {
+	return s.favorites.UpdateFavoriteNote(ctx, userID, listingID, note)
+}
+
+// CountFavorites counts all favorites across users
+func (s *FavoriteService) CountFavorites(ctx context.Context) (int64, error) {
+	return s.favorites.CountFavorites(ctx)
 }
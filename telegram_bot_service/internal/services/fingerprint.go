@@ -0,0 +1,118 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/models"
+	"unicode"
+)
+
+// priceBandRatio is the width of a price band: prices within a band differ by less than 5%
+const priceBandRatio = 1.05
+
+// photoHashMaxDistance is the largest number of differing bits of two photo hashes of the same flat
+const photoHashMaxDistance = 6
+
+// Fingerprint identifies a flat independently of who posted it and under which ID
+type Fingerprint struct {
+	// Key hashes the normalized address, floor, area and room count; empty when one of them is unknown
+	Key string
+	// PriceBand is the logarithmic price bucket; neighbouring bands also match, so a repost
+	// with a slightly different price is still found
+	PriceBand int
+	// PhotoHash is the average hash of the first photo, zero when not computed
+	PhotoHash uint64
+}
+
+var (
+	numberPattern = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+	// addressNoise are words that agents add or omit without changing the address
+	addressNoise = map[string]bool{
+		"россия": true, "москва": true, "г": true, "город": true, "ул": true, "улица": true,
+		"д": true, "дом": true, "пр": true, "просп": true, "проспект": true, "пер": true, "переулок": true,
+		"р-н": true, "район": true, "мкр": true,
+	}
+)
+
+// ListingFingerprint computes the fingerprint of a listing without the photo hash
+func ListingFingerprint(listing *models.Listing) Fingerprint {
+	address := normalizeAddress(listing.Address)
+	floor := firstNumber(listing.Floor)
+	area := firstNumber(listing.Area)
+	rooms := normalizeRooms(listing.Rooms)
+	fingerprint := Fingerprint{PriceBand: priceBand(listing.PriceValue)}
+	if address == "" || floor == "" || area == "" || rooms == "" || listing.PriceValue <= 0 {
+		return fingerprint
+	}
+
+	// Agents round the area differently, 45.5 and 46 m² are the same flat
+	areaValue, _ := strconv.ParseFloat(strings.Replace(area, ",", ".", 1), 64)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s", address, floor, int(math.Round(areaValue)), rooms)))
+	fingerprint.Key = hex.EncodeToString(sum[:8])
+	return fingerprint
+}
+
+// SameFlat reports whether two fingerprints most likely describe the same flat
+func (f Fingerprint) SameFlat(other Fingerprint) bool {
+	if abs(f.PriceBand-other.PriceBand) > 1 {
+		return false
+	}
+	if f.Key != "" && f.Key == other.Key {
+		return true
+	}
+	return f.PhotoHash != 0 && other.PhotoHash != 0 && hammingDistance(f.PhotoHash, other.PhotoHash) <= photoHashMaxDistance
+}
+
+func priceBand(price int) int {
+	if price <= 0 {
+		return 0
+	}
+	return int(math.Floor(math.Log(float64(price)) / math.Log(priceBandRatio)))
+}
+
+func normalizeAddress(address string) string {
+	address = strings.ReplaceAll(strings.ToLower(address), "ё", "е")
+	words := strings.FieldsFunc(address, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+
+	kept := words[:0]
+	for _, word := range words {
+		if !addressNoise[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func normalizeRooms(rooms string) string {
+	if strings.Contains(strings.ToLower(rooms), "студ") {
+		return "0"
+	}
+	return firstNumber(rooms)
+}
+
+func firstNumber(text string) string {
+	return numberPattern.FindString(text)
+}
+
+func hammingDistance(a, b uint64) int {
+	distance := 0
+	for x := a ^ b; x != 0; x &= x - 1 {
+		distance++
+	}
+	return distance
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
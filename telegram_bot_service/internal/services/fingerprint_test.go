@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"testing"
+
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/services"
+)
+
+func TestSameFlat(t *testing.T) {
+	original := models.Listing{
+		ID:         "1001",
+		Address:    "Россия, г. Москва, ул. Семёновская, д. 5",
+		Floor:      "3/9",
+		Area:       "45,5 м²",
+		Rooms:      "2-комн.",
+		PriceValue: 60000,
+	}
+
+	tests := []struct {
+		name   string
+		repost func(*models.Listing)
+		want   bool
+	}{
+		{"same flat with a new ID", func(l *models.Listing) { l.ID = "2002" }, true},
+		{"address spelled differently", func(l *models.Listing) { l.ID = "2002"; l.Address = "Семеновская улица 5" }, true},
+		{"area rounded", func(l *models.Listing) { l.Area = "46 м²" }, true},
+		{"slightly different price", func(l *models.Listing) { l.PriceValue = 62000 }, true},
+		{"different floor", func(l *models.Listing) { l.Floor = "4/9" }, false},
+		{"different area", func(l *models.Listing) { l.Area = "60 м²" }, false},
+		{"different room count", func(l *models.Listing) { l.Rooms = "3-комн." }, false},
+		{"studio in the same building", func(l *models.Listing) { l.Rooms = "Студия"; l.Area = "25 м²" }, false},
+		{"different house", func(l *models.Listing) { l.Address = "ул. Семёновская, д. 7" }, false},
+		{"much higher price", func(l *models.Listing) { l.PriceValue = 90000 }, false},
+		{"unknown floor", func(l *models.Listing) { l.Floor = "" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repost := original
+			tt.repost(&repost)
+
+			a, b := services.ListingFingerprint(&original), services.ListingFingerprint(&repost)
+			if got := a.SameFlat(b); got != tt.want {
+				t.Errorf("SameFlat = %v, want %v", got, tt.want)
+			}
+			if got := b.SameFlat(a); got != tt.want {
+				t.Errorf("SameFlat is not symmetric")
+			}
+		})
+	}
+}
+
+func TestListingFingerprintNeedsAllFields(t *testing.T) {
+	complete := models.Listing{Address: "ул. Лесная, 5", Floor: "2/5", Area: "30 м²", Rooms: "1-комн.", PriceValue: 40000}
+	if services.ListingFingerprint(&complete).Key == "" {
+		t.Fatal("complete listing has no fingerprint key")
+	}
+
+	for name, clear := range map[string]func(*models.Listing){
+		"address": func(l *models.Listing) { l.Address = "Москва" },
+		"floor":   func(l *models.Listing) { l.Floor = "" },
+		"area":    func(l *models.Listing) { l.Area = "" },
+		"rooms":   func(l *models.Listing) { l.Rooms = "" },
+		"price":   func(l *models.Listing) { l.PriceValue = 0 },
+	} {
+		listing := complete
+		clear(&listing)
+		if key := services.ListingFingerprint(&listing).Key; key != "" {
+			t.Errorf("listing without %s has key %q", name, key)
+		}
+	}
+}
+
+func TestSameFlatByPhotoHash(t *testing.T) {
+	const hash = 0xF0F0_F0F0_0F0F_0F0F
+
+	tests := []struct {
+		name  string
+		other services.Fingerprint
+		want  bool
+	}{
+		{"same photo", services.Fingerprint{PriceBand: 200, PhotoHash: hash}, true},
+		{"photo with a few bits changed", services.Fingerprint{PriceBand: 201, PhotoHash: hash ^ 0b111}, true},
+		{"different photo", services.Fingerprint{PriceBand: 200, PhotoHash: ^uint64(hash)}, false},
+		{"same photo, far price", services.Fingerprint{PriceBand: 210, PhotoHash: hash}, false},
+		{"no photo hash", services.Fingerprint{PriceBand: 200}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Different keys, e.g. an address the normalization doesn't catch
+			f := services.Fingerprint{Key: "a", PriceBand: 200, PhotoHash: hash}
+			tt.other.Key = "b"
+			if got := f.SameFlat(tt.other); got != tt.want {
+				t.Errorf("SameFlat = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if (services.Fingerprint{}).SameFlat(services.Fingerprint{}) {
+		t.Error("two empty fingerprints match")
+	}
+}
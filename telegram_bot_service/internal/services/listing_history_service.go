@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+	"time"
+)
+
+const (
+	// photoMatchWindow is how far back reposts are searched for by photo
+	photoMatchWindow = 30 * 24 * time.Hour
+	// photoHashWorkers bounds concurrent photo downloads
+	photoHashWorkers = 4
+)
+
+// ListingDiff is the result of comparing fetched listings with the stored history
+type ListingDiff struct {
+	// Listings are all fetched listings in the parser's order
+	Listings []models.Listing
+	// New are listings of flats not seen before, one per flat
+	New []models.Listing
+	// Duplicates maps the ID of a new listing to other new listings of the same flat,
+	// e.g. the same flat posted by several agents
+	Duplicates map[string][]models.Listing
+	// PriceChanges are known listings with a new price; they are shown by notify --once
+	// but subscribers are not notified about them
+	PriceChanges []PriceChange
+	// Reposts are new listings of flats that are already in the history
+	Reposts []Repost
+	// Baseline is set when the history was empty: the listings are recorded but none is reported as new,
+	// so the first run doesn't notify about everything currently on the market
+	Baseline bool
+
+	fingerprints map[string]Fingerprint
+	canonicalIDs map[string]string
+}
+
+// PriceChange is a known listing whose price changed since it was last seen
+type PriceChange struct {
+	Listing       models.Listing
+	OldPrice      string
+	OldPriceValue int
+}
+
+// Repost is a new listing recognized as another copy of a stored listing
+type Repost struct {
+	Listing     models.Listing
+	CanonicalID string
+}
+
+// Empty reports whether there is nothing to notify about
+func (d *ListingDiff) Empty() bool {
+	return len(d.New) == 0
+}
+
+// DuplicateCount returns the number of new listings collapsed into another listing of the same flat
+func (d *ListingDiff) DuplicateCount() int {
+	count := len(d.Reposts)
+	for _, duplicates := range d.Duplicates {
+		count += len(duplicates)
+	}
+	return count
+}
+
+type ListingHistoryService struct {
+	cian     *CianService
+	listings repository.ListingRepository
+	photos   *PhotoHasher
+}
+
+// NewListingHistoryService creates the history service; photos enables matching reposts by photo and may be nil
+func NewListingHistoryService(cian *CianService, listings repository.ListingRepository, photos *PhotoHasher) *ListingHistoryService {
+	return &ListingHistoryService{cian: cian, listings: listings, photos: photos}
+}
+
+// Poll fetches the current listings and compares them with the history without storing anything
+func (s *ListingHistoryService) Poll(ctx context.Context) (*ListingDiff, error) {
+	listings, err := s.cian.GetListings(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.Compare(ctx, listings)
+}
+
+// Compare finds flats that are new or changed their price since they were last recorded.
+// Listings of the same flat are collapsed into the first one, see Fingerprint.
+func (s *ListingHistoryService) Compare(ctx context.Context, listings []models.Listing) (*ListingDiff, error) {
+	diff := &ListingDiff{
+		Listings:     listings,
+		Duplicates:   make(map[string][]models.Listing),
+		fingerprints: make(map[string]Fingerprint, len(listings)),
+		canonicalIDs: make(map[string]string),
+	}
+
+	total, err := s.listings.CountListings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diff.Baseline = total == 0
+
+	ids := make([]string, 0, len(listings))
+	for i := range listings {
+		ids = append(ids, listings[i].ID)
+		diff.fingerprints[listings[i].ID] = ListingFingerprint(&listings[i])
+	}
+	known, err := s.listings.GetListings(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []models.Listing
+	seen := make(map[string]bool, len(listings))
+	for _, listing := range listings {
+		if seen[listing.ID] {
+			continue
+		}
+		seen[listing.ID] = true
+
+		stored, ok := known[listing.ID]
+		switch {
+		case !ok:
+			unknown = append(unknown, listing)
+		case stored.PriceValue > 0 && listing.PriceValue > 0 && stored.PriceValue != listing.PriceValue:
+			diff.PriceChanges = append(diff.PriceChanges, PriceChange{
+				Listing:       listing,
+				OldPrice:      stored.Price,
+				OldPriceValue: stored.PriceValue,
+			})
+		}
+	}
+
+	s.hashPhotos(ctx, unknown, diff.fingerprints)
+	candidates, err := s.duplicateCandidates(ctx, unknown, diff.fingerprints)
+	if err != nil {
+		return nil, err
+	}
+
+	var flats []string
+	for _, listing := range unknown {
+		fingerprint := diff.fingerprints[listing.ID]
+
+		if canonicalID := matchStored(fingerprint, candidates); canonicalID != "" {
+			diff.canonicalIDs[listing.ID] = canonicalID
+			diff.Reposts = append(diff.Reposts, Repost{Listing: listing, CanonicalID: canonicalID})
+			continue
+		}
+
+		if canonicalID := matchFlat(fingerprint, flats, diff.fingerprints); canonicalID != "" {
+			diff.canonicalIDs[listing.ID] = canonicalID
+			diff.Duplicates[canonicalID] = append(diff.Duplicates[canonicalID], listing)
+			continue
+		}
+
+		flats = append(flats, listing.ID)
+		if !diff.Baseline {
+			diff.New = append(diff.New, listing)
+		}
+	}
+
+	return diff, nil
+}
+
+// duplicateCandidates loads stored listings that new listings may be copies of
+func (s *ListingHistoryService) duplicateCandidates(ctx context.Context, unknown []models.Listing, fingerprints map[string]Fingerprint) ([]models.StoredListing, error) {
+	var keys []string
+	for _, listing := range unknown {
+		if key := fingerprints[listing.ID].Key; key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	candidates, err := s.listings.FindByFingerprints(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	if s.photos == nil {
+		return candidates, nil
+	}
+
+	withPhotos, err := s.listings.ListWithPhotoHash(ctx, time.Now().Add(-photoMatchWindow))
+	if err != nil {
+		return nil, err
+	}
+	return append(candidates, withPhotos...), nil
+}
+
+// hashPhotos adds the hashes of the first photos of listings to their fingerprints
+func (s *ListingHistoryService) hashPhotos(ctx context.Context, listings []models.Listing, fingerprints map[string]Fingerprint) {
+	if s.photos == nil {
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, photoHashWorkers)
+	for _, listing := range listings {
+		if len(listing.Photos) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(id, url string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			hash, err := s.photos.Hash(ctx, url)
+			if err != nil {
+				logging.FromContext(ctx).WithError(err).WithField("listing_id", id).Debug("Failed to hash listing photo")
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			fingerprint := fingerprints[id]
+			fingerprint.PhotoHash = hash
+			fingerprints[id] = fingerprint
+		}(listing.ID, listing.Photos[0])
+	}
+	wg.Wait()
+}
+
+// matchStored returns the canonical ID of a stored listing of the same flat
+func matchStored(fingerprint Fingerprint, candidates []models.StoredListing) string {
+	for _, candidate := range candidates {
+		stored := Fingerprint{Key: candidate.Fingerprint, PriceBand: candidate.PriceBand, PhotoHash: uint64(candidate.PhotoHash)}
+		if !fingerprint.SameFlat(stored) {
+			continue
+		}
+		if candidate.CanonicalID != "" {
+			return candidate.CanonicalID
+		}
+		return candidate.ID
+	}
+	return ""
+}
+
+// matchFlat returns the ID of an earlier listing of the same flat from the current batch
+func matchFlat(fingerprint Fingerprint, flats []string, fingerprints map[string]Fingerprint) string {
+	for _, id := range flats {
+		if fingerprint.SameFlat(fingerprints[id]) {
+			return id
+		}
+	}
+	return ""
+}
+
+// Record stores the listings of diff as seen at seenAt, linking duplicates to their canonical listing
+func (s *ListingHistoryService) Record(ctx context.Context, diff *ListingDiff, seenAt time.Time) error {
+	stored := make([]models.StoredListing, 0, len(diff.Listings))
+	seen := make(map[string]bool, len(diff.Listings))
+	for _, listing := range diff.Listings {
+		// PostgreSQL rejects an upsert touching the same row twice
+		if seen[listing.ID] {
+			continue
+		}
+		seen[listing.ID] = true
+
+		fingerprint := diff.fingerprints[listing.ID]
+		stored = append(stored, models.StoredListing{
+			ID:          listing.ID,
+			Title:       listing.Title,
+			Price:       listing.Price,
+			PriceValue:  listing.PriceValue,
+			Address:     listing.Address,
+			URL:         listing.URL,
+			Area:        listing.Area,
+			Rooms:       listing.Rooms,
+			Floor:       listing.Floor,
+			Metro:       listing.Metro,
+			District:    listing.District,
+			Fingerprint: fingerprint.Key,
+			PriceBand:   fingerprint.PriceBand,
+			PhotoHash:   int64(fingerprint.PhotoHash),
+			CanonicalID: diff.canonicalIDs[listing.ID],
+			FirstSeenAt: seenAt,
+			LastSeenAt:  seenAt,
+		})
+	}
+	return s.listings.SaveListings(ctx, stored)
+}
+
+// CountListings counts listings in the history
+func (s *ListingHistoryService) CountListings(ctx context.Context) (int64, error) {
+	return s.listings.CountListings(ctx)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+	"time"
+)
+
+const (
+	// marketWindow is the period the rolling medians cover
+	marketWindow = 30 * 24 * time.Hour
+	// marketRefreshInterval is how long computed medians are reused
+	marketRefreshInterval = 10 * time.Minute
+	// minMarketSamples is the smallest number of listings a local median is trusted with
+	minMarketSamples = 5
+)
+
+// Groups of similar listings, from the most to the least local
+const (
+	MarketMetro    = "metro"
+	MarketDistrict = "district"
+	MarketRooms    = "rooms"
+)
+
+var marketGroups = []string{MarketMetro, MarketDistrict, MarketRooms}
+
+// PriceComparison compares the price per square meter of a listing with similar listings
+type PriceComparison struct {
+	PricePerMeter float64
+	// Median is the median price per square meter of similar listings, 0 when there are too few of them
+	Median float64
+	// Group and Name describe the similar listings, e.g. MarketMetro and "Сокольники"
+	Group   string
+	Name    string
+	Samples int
+}
+
+// Deviation returns the relative difference from the median, e.g. -0.12 for 12% cheaper
+func (c *PriceComparison) Deviation() float64 {
+	if c.Median <= 0 {
+		return 0
+	}
+	return c.PricePerMeter/c.Median - 1
+}
+
+type marketMedian struct {
+	name    string
+	value   float64
+	samples int
+}
+
+// marketStats are the medians by group and normalized group key
+type marketStats map[string]map[string]marketMedian
+
+// MarketService keeps rolling medians of the price per square meter by metro station, district
+// and room count over the listings stored by the notifier
+type MarketService struct {
+	listings repository.ListingRepository
+
+	mu        sync.Mutex
+	stats     marketStats
+	updatedAt time.Time
+}
+
+func NewMarketService(listings repository.ListingRepository) *MarketService {
+	return &MarketService{listings: listings}
+}
+
+// Compare compares a listing with the most local group that has enough listings;
+// it returns nil when the price per square meter of the listing is unknown
+func (s *MarketService) Compare(ctx context.Context, listing *models.Listing) (*PriceComparison, error) {
+	ppm := PricePerMeter(listing)
+	if ppm <= 0 {
+		return nil, nil
+	}
+
+	stats, err := s.currentStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return stats.compare(listing, ppm), nil
+}
+
+func (stats marketStats) compare(listing *models.Listing, ppm float64) *PriceComparison {
+	comparison := &PriceComparison{PricePerMeter: ppm}
+	keys := marketKeys(listing.Metro, listing.District, listing.Rooms)
+	for _, group := range marketGroups {
+		if median, ok := stats[group][keys[group]]; ok && keys[group] != "" {
+			comparison.Median = median.value
+			comparison.Group = group
+			comparison.Name = median.name
+			comparison.Samples = median.samples
+			break
+		}
+	}
+	return comparison
+}
+
+// currentStats returns the medians, recomputing them when they are older than marketRefreshInterval
+func (s *MarketService) currentStats(ctx context.Context) (marketStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stats != nil && time.Since(s.updatedAt) < marketRefreshInterval {
+		return s.stats, nil
+	}
+
+	listings, err := s.listings.ListSeenSince(ctx, time.Now().Add(-marketWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	s.stats = computeMarketStats(listings)
+	s.updatedAt = time.Now()
+	return s.stats, nil
+}
+
+func computeMarketStats(listings []models.StoredListing) marketStats {
+	type sample struct {
+		name   string
+		values []float64
+	}
+	samples := make(map[string]map[string]*sample, len(marketGroups))
+	for _, group := range marketGroups {
+		samples[group] = make(map[string]*sample)
+	}
+
+	for _, stored := range listings {
+		// A flat posted by several agents would otherwise count several times
+		if stored.CanonicalID != "" {
+			continue
+		}
+		listing := models.Listing{PriceValue: stored.PriceValue, Area: stored.Area}
+		ppm := PricePerMeter(&listing)
+		if ppm <= 0 {
+			continue
+		}
+
+		names := map[string]string{MarketMetro: metroStation(stored.Metro), MarketDistrict: stored.District, MarketRooms: normalizeRooms(stored.Rooms)}
+		for group, key := range marketKeys(stored.Metro, stored.District, stored.Rooms) {
+			if key == "" {
+				continue
+			}
+			if samples[group][key] == nil {
+				samples[group][key] = &sample{name: names[group]}
+			}
+			samples[group][key].values = append(samples[group][key].values, ppm)
+		}
+	}
+
+	stats := make(marketStats, len(marketGroups))
+	for group, byKey := range samples {
+		stats[group] = make(map[string]marketMedian)
+		for key, sample := range byKey {
+			if len(sample.values) >= minMarketSamples {
+				stats[group][key] = marketMedian{name: sample.name, value: median(sample.values), samples: len(sample.values)}
+			}
+		}
+	}
+	return stats
+}
+
+// marketKeys returns the normalized keys of the groups a listing belongs to
+func marketKeys(metro, district, rooms string) map[string]string {
+	return map[string]string{
+		MarketMetro:    strings.ToLower(metroStation(metro)),
+		MarketDistrict: strings.ToLower(strings.TrimSpace(district)),
+		MarketRooms:    normalizeRooms(rooms),
+	}
+}
+
+// metroStation strips the walking time from a metro description like "Сокольники, 5 мин."
+func metroStation(metro string) string {
+	station, _, _ := strings.Cut(metro, ",")
+	return strings.TrimSpace(station)
+}
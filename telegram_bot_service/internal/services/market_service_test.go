@@ -0,0 +1,153 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+	"telegram_bot_service/internal/services"
+)
+
+// newMarket stores listings seen just now and returns a market service over them
+func newMarket(t *testing.T, listings []models.StoredListing) *services.MarketService {
+	t.Helper()
+
+	now := time.Now()
+	for i := range listings {
+		if listings[i].ID == "" {
+			listings[i].ID = fmt.Sprint(i + 1)
+		}
+		listings[i].FirstSeenAt = now
+		listings[i].LastSeenAt = now
+	}
+
+	repo := repository.NewMemoryListingRepository()
+	if err := repo.SaveListings(context.Background(), listings); err != nil {
+		t.Fatal(err)
+	}
+	return services.NewMarketService(repo)
+}
+
+// flats returns 10 m² listings with the given prices per square meter
+func flats(template models.StoredListing, pricesPerMeter ...int) []models.StoredListing {
+	listings := make([]models.StoredListing, 0, len(pricesPerMeter))
+	for _, ppm := range pricesPerMeter {
+		listing := template
+		listing.Area = "10 м²"
+		listing.PriceValue = ppm * 10
+		listings = append(listings, listing)
+	}
+	return listings
+}
+
+func TestMarketCompare(t *testing.T) {
+	sokolniki := models.StoredListing{Metro: "Сокольники, 5 мин.", District: "Сокольники", Rooms: "1-комн."}
+	noDistrict := models.StoredListing{Rooms: "2-комн."}
+
+	tests := []struct {
+		name    string
+		sample  []models.StoredListing
+		listing models.Listing
+		median  float64
+		group   string
+		samples int
+	}{
+		{
+			name:    "odd count",
+			sample:  flats(sokolniki, 100, 500, 300, 200, 400),
+			listing: models.Listing{Metro: "Сокольники, 10 мин.", Area: "10", PriceValue: 2000},
+			median:  300,
+			group:   services.MarketMetro,
+			samples: 5,
+		},
+		{
+			name:    "even count",
+			sample:  flats(sokolniki, 100, 600, 300, 200, 500, 400),
+			listing: models.Listing{Metro: "сокольники", Area: "10", PriceValue: 2000},
+			median:  350,
+			group:   services.MarketMetro,
+			samples: 6,
+		},
+		{
+			name:    "too few listings at the station fall back to the district",
+			sample:  append(flats(sokolniki, 100, 200, 300, 400), flats(models.StoredListing{District: "Сокольники"}, 500)...),
+			listing: models.Listing{Metro: "Сокольники", District: "Сокольники", Area: "10", PriceValue: 2000},
+			median:  300,
+			group:   services.MarketDistrict,
+			samples: 5,
+		},
+		{
+			name:    "listings without a district are compared by rooms",
+			sample:  flats(noDistrict, 100, 200, 300, 400, 500),
+			listing: models.Listing{Rooms: "2-комн.", Area: "10", PriceValue: 2000},
+			median:  300,
+			group:   services.MarketRooms,
+			samples: 5,
+		},
+		{
+			name: "zero area listings are skipped",
+			sample: append(flats(sokolniki, 100, 200, 300, 400),
+				models.StoredListing{Metro: "Сокольники", PriceValue: 5000},
+				models.StoredListing{Metro: "Сокольники", Area: "0 м²", PriceValue: 5000}),
+			listing: models.Listing{Metro: "Сокольники", Area: "10", PriceValue: 2000},
+		},
+		{
+			name: "copies of a flat count once",
+			sample: append(flats(sokolniki, 100, 200, 300, 400),
+				models.StoredListing{ID: "copy", Metro: "Сокольники", Area: "10", PriceValue: 5000, CanonicalID: "1"}),
+			listing: models.Listing{Metro: "Сокольники", Area: "10", PriceValue: 2000},
+		},
+		{
+			name:    "no listings",
+			listing: models.Listing{Metro: "Сокольники", Area: "10", PriceValue: 2000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison, err := newMarket(t, tt.sample).Compare(context.Background(), &tt.listing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if comparison == nil {
+				t.Fatal("no comparison for a listing with a price per square meter")
+			}
+			if comparison.Median != tt.median || comparison.Group != tt.group || comparison.Samples != tt.samples {
+				t.Errorf("comparison = %+v, want median %v in %q of %d", comparison, tt.median, tt.group, tt.samples)
+			}
+			if comparison.PricePerMeter != 200 {
+				t.Errorf("price per meter = %v, want 200", comparison.PricePerMeter)
+			}
+		})
+	}
+}
+
+func TestMarketCompareWithoutArea(t *testing.T) {
+	market := newMarket(t, flats(models.StoredListing{Rooms: "1-комн."}, 100, 200, 300, 400, 500))
+
+	comparison, err := market.Compare(context.Background(), &models.Listing{Rooms: "1-комн.", PriceValue: 2000})
+	if err != nil || comparison != nil {
+		t.Errorf("comparison without area = %+v, %v, want none", comparison, err)
+	}
+}
+
+func TestPriceComparisonDeviation(t *testing.T) {
+	tests := []struct {
+		comparison services.PriceComparison
+		want       float64
+	}{
+		{services.PriceComparison{PricePerMeter: 880, Median: 1000}, -0.12},
+		{services.PriceComparison{PricePerMeter: 1500, Median: 1000}, 0.5},
+		{services.PriceComparison{PricePerMeter: 1500}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.comparison.Deviation(); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v: deviation = %v, want %v", tt.comparison, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"telegram_bot_service/internal/models"
+	"time"
+)
+
+const (
+	// statsDays is the period covered by the market statistics
+	statsDays = 30
+	// statsMaxDistricts caps the districts compared in the statistics
+	statsMaxDistricts = 8
+	// delistedAfter is how long a listing must be missing from the checks to count as taken off the market
+	delistedAfter = 24 * time.Hour
+)
+
+// GroupMedian is the median rent of a group of listings
+type GroupMedian struct {
+	Name   string
+	Median int
+	Count  int
+}
+
+// DailyValue is a value of a day in a time series
+type DailyValue struct {
+	Day   time.Time
+	Value float64
+}
+
+// MarketStats describe the market over the last statsDays days as recorded in the listing history
+type MarketStats struct {
+	// Listings is the number of flats on the market during the period, without copies
+	Listings int
+	// RoomMedians are ordered by room count, "0" is a studio
+	RoomMedians []GroupMedian
+	// DistrictMedians are the districts with the most listings, the most expensive first
+	DistrictMedians []GroupMedian
+	// DailyNew counts listings first seen each day
+	DailyNew []DailyValue
+	// PriceTrend is the median rent of the listings on the market each day
+	PriceTrend []DailyValue
+	// TimeOnMarket is the average time until a listing was taken off the market,
+	// over Delisted listings; zero when none was
+	TimeOnMarket time.Duration
+	Delisted     int
+}
+
+// Stats computes market statistics from the stored listings. Days before the history started are
+// left out, as is the day of the first check, which records every listing already on the market.
+func (s *MarketService) Stats(ctx context.Context) (*MarketStats, error) {
+	now := time.Now()
+	today := startOfDay(now)
+	from := today.AddDate(0, 0, -(statsDays - 1))
+
+	stored, err := s.listings.ListSeenSince(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var listings []models.StoredListing
+	var historyStart, lastCheck time.Time
+	for _, listing := range stored {
+		if historyStart.IsZero() || listing.FirstSeenAt.Before(historyStart) {
+			historyStart = listing.FirstSeenAt
+		}
+		if listing.LastSeenAt.After(lastCheck) {
+			lastCheck = listing.LastSeenAt
+		}
+		if listing.CanonicalID == "" {
+			listings = append(listings, listing)
+		}
+	}
+
+	stats := &MarketStats{Listings: len(listings)}
+	if len(listings) == 0 {
+		return stats, nil
+	}
+
+	stats.RoomMedians = groupMedians(listings, func(listing *models.StoredListing) string {
+		return normalizeRooms(listing.Rooms)
+	})
+	sort.SliceStable(stats.RoomMedians, func(i, j int) bool {
+		a, _ := strconv.ParseFloat(stats.RoomMedians[i].Name, 64)
+		b, _ := strconv.ParseFloat(stats.RoomMedians[j].Name, 64)
+		return a < b
+	})
+
+	stats.DistrictMedians = groupMedians(listings, func(listing *models.StoredListing) string {
+		return listing.District
+	})
+	sort.SliceStable(stats.DistrictMedians, func(i, j int) bool {
+		return stats.DistrictMedians[i].Count > stats.DistrictMedians[j].Count
+	})
+	if len(stats.DistrictMedians) > statsMaxDistricts {
+		stats.DistrictMedians = stats.DistrictMedians[:statsMaxDistricts]
+	}
+	sort.SliceStable(stats.DistrictMedians, func(i, j int) bool {
+		return stats.DistrictMedians[i].Median > stats.DistrictMedians[j].Median
+	})
+
+	firstDay := startOfDay(historyStart).AddDate(0, 0, 1)
+	if firstDay.Before(from) {
+		firstDay = from
+	}
+	for day := firstDay; !day.After(today); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+
+		var added int
+		var prices []float64
+		for _, listing := range listings {
+			if !listing.FirstSeenAt.Before(day) && listing.FirstSeenAt.Before(next) {
+				added++
+			}
+			if listing.FirstSeenAt.Before(next) && !listing.LastSeenAt.Before(day) && listing.PriceValue > 0 {
+				prices = append(prices, float64(listing.PriceValue))
+			}
+		}
+
+		stats.DailyNew = append(stats.DailyNew, DailyValue{Day: day, Value: float64(added)})
+		if len(prices) > 0 {
+			stats.PriceTrend = append(stats.PriceTrend, DailyValue{Day: day, Value: median(prices)})
+		}
+	}
+
+	var onMarket time.Duration
+	for _, listing := range listings {
+		// Listings from the first check were on the market for an unknown time before it
+		if startOfDay(listing.FirstSeenAt).Equal(startOfDay(historyStart)) {
+			continue
+		}
+		if lastCheck.Sub(listing.LastSeenAt) >= delistedAfter {
+			onMarket += listing.LastSeenAt.Sub(listing.FirstSeenAt)
+			stats.Delisted++
+		}
+	}
+	if stats.Delisted > 0 {
+		stats.TimeOnMarket = onMarket / time.Duration(stats.Delisted)
+	}
+
+	return stats, nil
+}
+
+// groupMedians returns the median rent of the listings by the key, skipping listings without one
+func groupMedians(listings []models.StoredListing, key func(listing *models.StoredListing) string) []GroupMedian {
+	prices := make(map[string][]float64)
+	for i := range listings {
+		name := key(&listings[i])
+		if name == "" || listings[i].PriceValue <= 0 {
+			continue
+		}
+		prices[name] = append(prices[name], float64(listings[i].PriceValue))
+	}
+
+	medians := make([]GroupMedian, 0, len(prices))
+	for name, values := range prices {
+		medians = append(medians, GroupMedian{Name: name, Median: int(median(values)), Count: len(values)})
+	}
+	// Map order is random; ties in the callers' sorting must not reorder the charts between calls
+	sort.Slice(medians, func(i, j int) bool {
+		return medians[i].Name < medians[j].Name
+	})
+	return medians
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/services"
+)
+
+func TestMarketStatsMedians(t *testing.T) {
+	market := newMarket(t, []models.StoredListing{
+		{Rooms: "1-комн.", District: "Арбат", PriceValue: 50000},
+		{Rooms: "1-комн.", District: "Арбат", PriceValue: 30000},
+		{Rooms: "1-комн.", District: "Арбат", PriceValue: 40000},
+		{Rooms: "2-комн.", District: "Митино", PriceValue: 80000},
+		{Rooms: "2-комн.", District: "Митино", PriceValue: 50000},
+		{Rooms: "2-комн.", PriceValue: 70000},
+		{Rooms: "2-комн.", PriceValue: 60000},
+		{Rooms: "Студия", District: "Митино"},
+		{ID: "copy", Rooms: "Студия", District: "Арбат", PriceValue: 10000, CanonicalID: "1"},
+	})
+
+	stats, err := market.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Listings != 8 {
+		t.Errorf("listings = %d, want 8 without the copy", stats.Listings)
+	}
+
+	// An odd and an even count; the studio without a price has no median
+	wantRooms := []services.GroupMedian{
+		{Name: "1", Median: 40000, Count: 3},
+		{Name: "2", Median: 65000, Count: 4},
+	}
+	if !reflect.DeepEqual(stats.RoomMedians, wantRooms) {
+		t.Errorf("room medians = %+v, want %+v", stats.RoomMedians, wantRooms)
+	}
+
+	// Listings without a district are left out, the most expensive district comes first
+	wantDistricts := []services.GroupMedian{
+		{Name: "Митино", Median: 65000, Count: 2},
+		{Name: "Арбат", Median: 40000, Count: 3},
+	}
+	if !reflect.DeepEqual(stats.DistrictMedians, wantDistricts) {
+		t.Errorf("district medians = %+v, want %+v", stats.DistrictMedians, wantDistricts)
+	}
+}
+
+func TestMarketStatsWithoutListings(t *testing.T) {
+	stats, err := newMarket(t, nil).Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Listings != 0 || stats.RoomMedians != nil || stats.DistrictMedians != nil || stats.Delisted != 0 {
+		t.Errorf("stats without listings = %+v", stats)
+	}
+}
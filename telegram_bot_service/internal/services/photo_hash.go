@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxPhotoSize bounds the download of a single photo
+const maxPhotoSize = 10 << 20
+
+// PhotoHasher computes perceptual hashes of listing photos, so a repost with a different address
+// spelling is still recognized by its pictures
+type PhotoHasher struct {
+	httpClient *http.Client
+}
+
+func NewPhotoHasher() *PhotoHasher {
+	return &PhotoHasher{httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Hash downloads the photo at url and returns its 64-bit average hash
+func (h *PhotoHasher) Hash(ctx context.Context, url string) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("photo returned status %d", resp.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxPhotoSize))
+	if err != nil {
+		return 0, err
+	}
+	return averageHash(img), nil
+}
+
+// averageHash shrinks the image to 8x8 gray cells and sets a bit for every cell brighter than the mean
+func averageHash(img image.Image) uint64 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return 0
+	}
+
+	var cells [64]float64
+	var counts [64]int
+	// Sampling every few pixels is plenty for an 8x8 summary of a large photo
+	step := max(1, min(width, height)/64)
+	for y := 0; y < height; y += step {
+		for x := 0; x < width; x += step {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			cell := (y*8/height)*8 + x*8/width
+			cells[cell] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[cell]++
+		}
+	}
+
+	var mean float64
+	for i := range cells {
+		if counts[i] > 0 {
+			cells[i] /= float64(counts[i])
+		}
+		mean += cells[i]
+	}
+	mean /= 64
+
+	var hash uint64
+	for i, value := range cells {
+		if value > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
@@ -0,0 +1,129 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"telegram_bot_service/internal/services"
+)
+
+// photo draws a test picture at any size: a bright window on the right, a darker floor below
+func photo(width, height int, mirrored bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			if mirrored {
+				fx = 1 - fx
+			}
+			level := uint8(40 + 60*fy)
+			if fx > 0.55 && fy < 0.6 {
+				level = 230
+			} else if fy > 0.8 {
+				level = 20
+			}
+			img.Set(x, y, color.RGBA{R: level, G: level, B: level, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// photoServer serves the given files by path
+func photoServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPhotoHash(t *testing.T) {
+	server := photoServer(t, map[string][]byte{
+		"/original.png": encodePNG(t, photo(640, 480, false)),
+		"/small.png":    encodePNG(t, photo(320, 240, false)),
+		"/resaved.jpg":  encodeJPEG(t, photo(800, 600, false)),
+		"/mirrored.png": encodePNG(t, photo(640, 480, true)),
+	})
+	hasher := services.NewPhotoHasher()
+
+	hash := func(path string) uint64 {
+		t.Helper()
+		value, err := hasher.Hash(context.Background(), server.URL+path)
+		if err != nil {
+			t.Fatalf("hashing %s: %v", path, err)
+		}
+		return value
+	}
+
+	original := hash("/original.png")
+	if original == 0 {
+		t.Fatal("photo hashed to zero")
+	}
+
+	tests := []struct {
+		path string
+		same bool
+	}{
+		{"/small.png", true},
+		{"/resaved.jpg", true},
+		{"/mirrored.png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			other := hash(tt.path)
+			a := services.Fingerprint{PhotoHash: original}
+			b := services.Fingerprint{PhotoHash: other}
+			if got := a.SameFlat(b); got != tt.same {
+				t.Errorf("SameFlat = %v, want %v (%d bits differ)", got, tt.same, bits.OnesCount64(original^other))
+			}
+		})
+	}
+}
+
+func TestPhotoHashErrors(t *testing.T) {
+	server := photoServer(t, map[string][]byte{
+		"/text.png": []byte("not an image"),
+	})
+	hasher := services.NewPhotoHasher()
+
+	for _, path := range []string{"/missing.png", "/text.png"} {
+		if _, err := hasher.Hash(context.Background(), server.URL+path); err == nil {
+			t.Errorf("%s was hashed without an error", path)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+	"time"
+)
+
+// Score components; their names are the keys of RankingSettings.Weights and of the /ranking command
+const (
+	RankPricePerMeter = "price"
+	RankFloor         = "floor"
+	RankMetro         = "metro"
+	RankAuthor        = "author"
+	RankCommission    = "commission"
+	RankFreshness     = "freshness"
+)
+
+// RankingComponents lists the score components in display order
+var RankingComponents = []string{RankPricePerMeter, RankFloor, RankMetro, RankAuthor, RankCommission, RankFreshness}
+
+// Floor preferences
+const (
+	FloorMiddle = "middle" // neither the first nor the last floor
+	FloorHigh   = "high"
+	FloorLow    = "low"
+)
+
+const (
+	// MaxRankingWeight is the largest weight of a score component
+	MaxRankingWeight = 10
+	// freshnessHalfLife is the age at which a listing loses half of its freshness score
+	freshnessHalfLife = 3 * 24 * time.Hour
+	// minDistrictListings is the smallest sample for a district median; smaller districts use the overall one
+	minDistrictListings = 3
+)
+
+var (
+	defaultRankingWeights = map[string]int{
+		RankPricePerMeter: 3,
+		RankFloor:         1,
+		RankMetro:         2,
+		RankAuthor:        1,
+		RankCommission:    2,
+		RankFreshness:     1,
+	}
+
+	metroMinutesPattern = regexp.MustCompile(`(\d+)\s*мин`)
+
+	ownerAuthorTypes = map[string]bool{"homeowner": true, "owner": true, "собственник": true}
+	agentAuthorTypes = map[string]bool{"realtor": true, "real_estate_agent": true, "agent": true, "агент": true, "риелтор": true}
+)
+
+// RankingSettings are a user's preferences for ordering listings
+type RankingSettings struct {
+	// Weights of the score components from 0 to MaxRankingWeight; 0 ignores a component
+	Weights map[string]int `json:"weights"`
+	// Floor is FloorMiddle, FloorHigh or FloorLow
+	Floor string `json:"floor"`
+	// Metro are preferred stations; listings near them get the full metro score
+	Metro []string `json:"metro,omitempty"`
+}
+
+// DefaultRankingSettings returns the settings of users who never changed them
+func DefaultRankingSettings() RankingSettings {
+	weights := make(map[string]int, len(defaultRankingWeights))
+	for component, weight := range defaultRankingWeights {
+		weights[component] = weight
+	}
+	return RankingSettings{Weights: weights, Floor: FloorMiddle}
+}
+
+// withDefaults fills in components and preferences missing from stored settings, e.g. added later
+func (s RankingSettings) withDefaults() RankingSettings {
+	defaults := DefaultRankingSettings()
+	for component, weight := range s.Weights {
+		if _, ok := defaults.Weights[component]; ok {
+			defaults.Weights[component] = weight
+		}
+	}
+	s.Weights = defaults.Weights
+	if s.Floor != FloorHigh && s.Floor != FloorLow {
+		s.Floor = FloorMiddle
+	}
+	return s
+}
+
+type RankingService struct {
+	users    repository.UserRepository
+	listings repository.ListingRepository
+	market   *MarketService
+}
+
+// NewRankingService creates the ranking service; market provides the medians of the price per
+// square meter and may be nil, then the medians of the ranked listings themselves are used
+func NewRankingService(users repository.UserRepository, listings repository.ListingRepository, market *MarketService) *RankingService {
+	return &RankingService{users: users, listings: listings, market: market}
+}
+
+// Settings returns the ranking settings of a user, the defaults if there are none
+func (s *RankingService) Settings(ctx context.Context, userID int64) (RankingSettings, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return DefaultRankingSettings(), nil
+	} else if err != nil {
+		return RankingSettings{}, err
+	}
+
+	settings, err := decodeUserSettings(user.Settings)
+	if err != nil {
+		return RankingSettings{}, err
+	}
+
+	var ranking RankingSettings
+	if raw, ok := settings["ranking"]; ok {
+		if err := json.Unmarshal(raw, &ranking); err != nil {
+			return RankingSettings{}, err
+		}
+	}
+	return ranking.withDefaults(), nil
+}
+
+// SaveSettings stores the ranking settings of a user, keeping the rest of the user's settings
+func (s *RankingService) SaveSettings(ctx context.Context, userID int64, ranking RankingSettings) error {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	settings, err := decodeUserSettings(user.Settings)
+	if err != nil {
+		return err
+	}
+	settings["ranking"], err = json.Marshal(ranking.withDefaults())
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return s.users.UpdateUserSettings(ctx, userID, string(encoded))
+}
+
+func decodeUserSettings(raw string) (map[string]json.RawMessage, error) {
+	settings := make(map[string]json.RawMessage)
+	if strings.TrimSpace(raw) == "" {
+		return settings, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Rank orders listings by the user's score, best first, and returns the scores keyed by listing ID
+func (s *RankingService) Rank(ctx context.Context, userID int64, listings []models.Listing) ([]models.Listing, map[string]int, error) {
+	settings, err := s.Settings(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	scorer, err := s.NewScorer(ctx, listings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ranked, scores := scorer.Rank(settings, listings)
+	return ranked, scores, nil
+}
+
+// Scorer scores listings against a market sample; it is shared by all users
+type Scorer struct {
+	now       time.Time
+	firstSeen map[string]time.Time
+	// market are the rolling medians from the history, preferred over the sample ones
+	market marketStats
+	// medians of the price per square meter by district; "" is the median of the whole sample
+	medians map[string]float64
+}
+
+// NewScorer prepares scoring with listings as the market sample, e.g. all current listings
+func (s *RankingService) NewScorer(ctx context.Context, listings []models.Listing) (*Scorer, error) {
+	ids := make([]string, 0, len(listings))
+	for i := range listings {
+		ids = append(ids, listings[i].ID)
+	}
+	stored, err := s.listings.GetListings(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	firstSeen := make(map[string]time.Time, len(stored))
+	for id, listing := range stored {
+		firstSeen[id] = listing.FirstSeenAt
+	}
+
+	samples := make(map[string][]float64)
+	for i := range listings {
+		ppm := PricePerMeter(&listings[i])
+		if ppm <= 0 {
+			continue
+		}
+		samples[""] = append(samples[""], ppm)
+		if listings[i].District != "" {
+			samples[listings[i].District] = append(samples[listings[i].District], ppm)
+		}
+	}
+	medians := make(map[string]float64, len(samples))
+	for district, values := range samples {
+		if district == "" || len(values) >= minDistrictListings {
+			medians[district] = median(values)
+		}
+	}
+
+	var market marketStats
+	if s.market != nil {
+		if market, err = s.market.currentStats(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Scorer{now: time.Now(), firstSeen: firstSeen, market: market, medians: medians}, nil
+}
+
+// Rank orders listings by score, best first, keeping the original order of equal scores
+func (sc *Scorer) Rank(settings RankingSettings, listings []models.Listing) ([]models.Listing, map[string]int) {
+	scores := make(map[string]int, len(listings))
+	for i := range listings {
+		scores[listings[i].ID] = sc.Score(settings, &listings[i])
+	}
+
+	ranked := append([]models.Listing(nil), listings...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].ID] > scores[ranked[j].ID]
+	})
+	return ranked, scores
+}
+
+// Score rates a listing from 0 to 100 as the weighted mean of the component scores.
+// Components without data score 0.5, so missing fields neither help nor hurt much.
+func (sc *Scorer) Score(settings RankingSettings, listing *models.Listing) int {
+	settings = settings.withDefaults()
+	components := map[string]float64{
+		RankPricePerMeter: sc.pricePerMeterScore(listing),
+		RankFloor:         floorScore(listing.Floor, settings.Floor),
+		RankMetro:         metroScore(listing.Metro, settings.Metro),
+		RankAuthor:        authorScore(listing.AuthorType),
+		RankCommission:    commissionScore(listing.Commission),
+		RankFreshness:     sc.freshnessScore(listing),
+	}
+
+	var sum, total float64
+	for component, value := range components {
+		weight := float64(settings.Weights[component])
+		sum += weight * value
+		total += weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return int(math.Round(100 * sum / total))
+}
+
+// pricePerMeterScore compares the price per square meter with the local median: the median
+// scores 0.5, half the median or less scores 1
+func (sc *Scorer) pricePerMeterScore(listing *models.Listing) float64 {
+	ppm := PricePerMeter(listing)
+	if ppm <= 0 {
+		return 0.5
+	}
+
+	median := sc.market.compare(listing, ppm).Median
+	if median <= 0 {
+		var ok bool
+		if median, ok = sc.medians[listing.District]; !ok {
+			median = sc.medians[""]
+		}
+	}
+	if median <= 0 {
+		return 0.5
+	}
+	return clamp(0.5 + (median-ppm)/median)
+}
+
+func (sc *Scorer) freshnessScore(listing *models.Listing) float64 {
+	published, ok := parsePublishedAt(listing.PublishedAt)
+	if !ok {
+		published, ok = sc.firstSeen[listing.ID]
+	}
+	if !ok {
+		// Not in the history yet, so the notifier hasn't seen it before: it is new
+		return 1
+	}
+
+	age := sc.now.Sub(published)
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(freshnessHalfLife))
+}
+
+func parsePublishedAt(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02", "02.01.2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func floorScore(floor, preference string) float64 {
+	numbers := numberPattern.FindAllString(floor, 2)
+	if len(numbers) == 0 {
+		return 0.5
+	}
+	current, _ := strconv.Atoi(numbers[0])
+	total := 0
+	if len(numbers) == 2 {
+		total, _ = strconv.Atoi(numbers[1])
+	}
+
+	// Relative height in the building from 0 (first floor) to 1 (last floor)
+	height := clamp(float64(current-1) / 9)
+	if total > 1 {
+		height = clamp(float64(current-1) / float64(total-1))
+	}
+
+	switch preference {
+	case FloorHigh:
+		return height
+	case FloorLow:
+		return 1 - height
+	default:
+		switch {
+		case current <= 1:
+			return 0
+		case total > 0 && current >= total:
+			return 0.5
+		default:
+			return 1
+		}
+	}
+}
+
+// metroScore prefers the user's stations and, failing that, a short walk: 5 minutes or less
+// scores 1, 30 minutes or more scores 0
+func metroScore(metro string, preferred []string) float64 {
+	if metro == "" {
+		return 0.5
+	}
+
+	lower := strings.ToLower(metro)
+	for _, station := range preferred {
+		if station != "" && strings.Contains(lower, strings.ToLower(station)) {
+			return 1
+		}
+	}
+
+	score := 0.5
+	if match := metroMinutesPattern.FindStringSubmatch(lower); match != nil {
+		minutes, _ := strconv.Atoi(match[1])
+		score = clamp(1 - float64(minutes-5)/25)
+	}
+	if len(preferred) > 0 {
+		// Some other station is worth less than one the user asked for
+		score /= 2
+	}
+	return score
+}
+
+func authorScore(authorType string) float64 {
+	authorType = strings.ToLower(authorType)
+	switch {
+	case ownerAuthorTypes[authorType]:
+		return 1
+	case agentAuthorTypes[authorType]:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+func commissionScore(commission int) float64 {
+	return clamp(1 - float64(commission)/100)
+}
+
+// PricePerMeter returns the monthly price per square meter, 0 when the price or area is unknown
+func PricePerMeter(listing *models.Listing) float64 {
+	area := listingArea(listing)
+	if area <= 0 || listing.PriceValue <= 0 {
+		return 0
+	}
+	return float64(listing.PriceValue) / area
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func clamp(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
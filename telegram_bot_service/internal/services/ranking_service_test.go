@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+	"telegram_bot_service/internal/services"
+)
+
+// newScorer scores against three listings of 40 m² at 1000, 1500 and 2000 ₽ per m²
+func newScorer(t *testing.T) *services.Scorer {
+	t.Helper()
+
+	ranking := services.NewRankingService(repository.NewMemoryUserRepository(), repository.NewMemoryListingRepository(), nil)
+	sample := []models.Listing{
+		{ID: "1", Area: "40 м²", PriceValue: 40000},
+		{ID: "2", Area: "40 м²", PriceValue: 60000},
+		{ID: "3", Area: "40 м²", PriceValue: 80000},
+	}
+	scorer, err := ranking.NewScorer(context.Background(), sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scorer
+}
+
+// weighted returns the default settings with only the given components weighed
+func weighted(weights map[string]int) services.RankingSettings {
+	settings := services.DefaultRankingSettings()
+	for component := range settings.Weights {
+		settings.Weights[component] = weights[component]
+	}
+	return settings
+}
+
+func TestScoreComponents(t *testing.T) {
+	scorer := newScorer(t)
+	threeDaysAgo := time.Now().Add(-72 * time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		component string
+		settings  func(*services.RankingSettings)
+		listing   models.Listing
+		want      int
+	}{
+		{"price at the median", services.RankPricePerMeter, nil, models.Listing{Area: "40", PriceValue: 60000}, 50},
+		{"price at half the median", services.RankPricePerMeter, nil, models.Listing{Area: "40", PriceValue: 30000}, 100},
+		{"price far above the median", services.RankPricePerMeter, nil, models.Listing{Area: "40", PriceValue: 120000}, 0},
+		{"price without area", services.RankPricePerMeter, nil, models.Listing{PriceValue: 60000}, 50},
+
+		{"first floor", services.RankFloor, nil, models.Listing{Floor: "1/9"}, 0},
+		{"middle floor", services.RankFloor, nil, models.Listing{Floor: "5/9"}, 100},
+		{"last floor", services.RankFloor, nil, models.Listing{Floor: "9/9"}, 50},
+		{"last floor preferring high", services.RankFloor, func(s *services.RankingSettings) { s.Floor = services.FloorHigh }, models.Listing{Floor: "9/9"}, 100},
+		{"last floor preferring low", services.RankFloor, func(s *services.RankingSettings) { s.Floor = services.FloorLow }, models.Listing{Floor: "9/9"}, 0},
+		{"unknown floor", services.RankFloor, nil, models.Listing{}, 50},
+
+		{"metro 5 minutes away", services.RankMetro, nil, models.Listing{Metro: "Сокольники, 5 мин"}, 100},
+		{"metro 30 minutes away", services.RankMetro, nil, models.Listing{Metro: "Сокольники, 30 мин"}, 0},
+		{"metro without minutes", services.RankMetro, nil, models.Listing{Metro: "Сокольники"}, 50},
+		{"preferred station", services.RankMetro, func(s *services.RankingSettings) { s.Metro = []string{"сокольники"} }, models.Listing{Metro: "Сокольники, 25 мин"}, 100},
+		{"other station", services.RankMetro, func(s *services.RankingSettings) { s.Metro = []string{"сокольники"} }, models.Listing{Metro: "Арбатская, 5 мин"}, 50},
+		{"unknown metro", services.RankMetro, nil, models.Listing{}, 50},
+		{"unknown metro with preferred stations", services.RankMetro, func(s *services.RankingSettings) { s.Metro = []string{"сокольники"} }, models.Listing{}, 50},
+
+		{"owner", services.RankAuthor, nil, models.Listing{AuthorType: "homeowner"}, 100},
+		{"agent", services.RankAuthor, nil, models.Listing{AuthorType: "realtor"}, 0},
+		{"unknown author", services.RankAuthor, nil, models.Listing{}, 50},
+
+		{"no commission", services.RankCommission, nil, models.Listing{}, 100},
+		{"half commission", services.RankCommission, nil, models.Listing{Commission: 50}, 50},
+		{"full commission", services.RankCommission, nil, models.Listing{Commission: 100}, 0},
+
+		{"published just now", services.RankFreshness, nil, models.Listing{PublishedAt: time.Now().Format(time.RFC3339)}, 100},
+		{"published a half-life ago", services.RankFreshness, nil, models.Listing{PublishedAt: threeDaysAgo}, 50},
+		{"never seen before", services.RankFreshness, nil, models.Listing{ID: "new"}, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A single weighed component makes the score that component's value
+			settings := weighted(map[string]int{tt.component: 1})
+			if tt.settings != nil {
+				tt.settings(&settings)
+			}
+			if got := scorer.Score(settings, &tt.listing); got != tt.want {
+				t.Errorf("score = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreWeightedTotal(t *testing.T) {
+	scorer := newScorer(t)
+
+	tests := []struct {
+		name     string
+		settings services.RankingSettings
+		listing  models.Listing
+		want     int
+	}{
+		{
+			name:     "equal weights",
+			settings: weighted(map[string]int{services.RankPricePerMeter: 1, services.RankCommission: 1}),
+			listing:  models.Listing{Area: "40", PriceValue: 60000},
+			want:     75,
+		},
+		{
+			name:     "heavier component dominates",
+			settings: weighted(map[string]int{services.RankPricePerMeter: 3, services.RankAuthor: 1}),
+			listing:  models.Listing{Area: "40", PriceValue: 30000, AuthorType: "realtor"},
+			want:     75,
+		},
+		{
+			name:     "all weights zero",
+			settings: weighted(nil),
+			listing:  models.Listing{Area: "40", PriceValue: 30000},
+			want:     0,
+		},
+		{
+			// Missing price, floor, metro and author score 0.5, no commission and a new listing score 1
+			name:     "no data with the default weights",
+			settings: services.DefaultRankingSettings(),
+			listing:  models.Listing{ID: "new"},
+			want:     65,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scorer.Score(tt.settings, &tt.listing); got != tt.want {
+				t.Errorf("score = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,766 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/models"
+	"unicode/utf8"
+)
+
+// SearchQuery is a parsed /search query such as "2к до 60000 метро Сокольники этаж>3".
+// Zero values don't filter; bounds are inclusive.
+type SearchQuery struct {
+	// Rooms are the accepted room counts, 0 is a studio
+	Rooms    []int
+	MinPrice int
+	MaxPrice int
+	MinArea  float64
+	MaxArea  float64
+	MinFloor int
+	MaxFloor int
+	// NotFirstFloor and NotLastFloor exclude the first and the last floor of the building
+	NotFirstFloor bool
+	NotLastFloor  bool
+	NoCommission  bool
+	OwnerOnly     bool
+	Metro         []string
+	Districts     []string
+	// Words must all appear in the title, address, description, metro or district
+	Words []string
+}
+
+// QueryError describes why a query can't be parsed and how to fix it
+type QueryError struct {
+	Token      string
+	Message    string
+	Suggestion string
+}
+
+func (e *QueryError) Error() string {
+	return e.Message
+}
+
+// SearchExamples are queries shown to users who need help with the syntax
+var SearchExamples = []string{
+	"2к до 60000 метро Сокольники этаж>3",
+	"студия от 30000 до 45000 без комиссии",
+	"1-2к площадь>=35 район Хамовники не первый",
+	"3к до 120 тыс собственник",
+}
+
+const (
+	maxQueryRooms = 9
+	// minQueryPrice separates prices from other numbers in ranges like 40000-60000
+	minQueryPrice = 1000
+)
+
+var (
+	searchTokenPattern  = regexp.MustCompile(`>=|<=|[<>=]|,|\d+(?:[.,]\d+)*(?:-\d+(?:[.,]\d+)*)?[^\s<>=,]*|[^\s<>=,]+`)
+	searchNumberPattern = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)(?:-(\d+(?:[.,]\d+)?))?(.*)$`)
+	// groupedNumberPattern matches numbers with thousands separated by spaces, like "50 000"
+	groupedNumberPattern = regexp.MustCompile(`\b\d{1,3}(?:[ \x{a0}\x{202f}]\d{3})+\b`)
+
+	roomSuffixes     = map[string]bool{"к": true, "к.": true, "-к": true, "кк": true, "комн": true, "комн.": true, "-комн": true, "-комн.": true, "комнатная": true, "-комнатная": true, "комнатную": true, "-комнатную": true, "комнаты": true, "комнат": true}
+	thousandSuffixes = map[string]bool{"к": true, "т": true, "т.": true, "тыс": true, "тыс.": true}
+	areaSuffixes     = map[string]bool{"м": true, "м2": true, "м²": true, "кв.м": true, "кв.м.": true, "метров": true}
+	rubleSuffixes    = map[string]bool{"": true, "₽": true, "р": true, "р.": true, "руб": true, "руб.": true, "рублей": true}
+
+	// searchStopWords carry no meaning in a query
+	searchStopWords = map[string]bool{
+		"в": true, "на": true, "и": true, "у": true, "с": true, "около": true, "рядом": true,
+		"квартира": true, "квартиру": true, "квартиры": true, "аренда": true, "снять": true,
+		"₽": true, "р": true, "руб": true, "рублей": true,
+	}
+
+	// searchKeywords are suggested for misspelled words
+	searchKeywords = []string{"до", "от", "цена", "этаж", "площадь", "метро", "район", "студия", "без комиссии", "собственник", "не первый", "не последний"}
+)
+
+// ParseSearchQuery parses a query; words that are not part of the grammar are searched as text
+func ParseSearchQuery(input string) (*SearchQuery, error) {
+	input = strings.ReplaceAll(strings.ToLower(input), "ё", "е")
+	input = groupedNumberPattern.ReplaceAllStringFunc(input, func(number string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, number)
+	})
+	p := &queryParser{tokens: searchTokenPattern.FindAllString(input, -1)}
+	if len(p.tokens) == 0 {
+		return nil, &QueryError{Message: "Пустой запрос.", Suggestion: "Например: " + SearchExamples[0]}
+	}
+
+	query := &SearchQuery{}
+	for !p.done() {
+		if err := p.parseTerm(query); err != nil {
+			return nil, err
+		}
+	}
+
+	if query.MinPrice > 0 && query.MaxPrice > 0 && query.MinPrice > query.MaxPrice {
+		return nil, &QueryError{Message: "Минимальная цена больше максимальной.", Suggestion: "Например: от 40000 до 60000"}
+	}
+	if query.MinFloor > 0 && query.MaxFloor > 0 && query.MinFloor > query.MaxFloor {
+		return nil, &QueryError{Message: "Минимальный этаж больше максимального.", Suggestion: "Например: этаж 3-10"}
+	}
+	if query.MinArea > 0 && query.MaxArea > 0 && query.MinArea > query.MaxArea {
+		return nil, &QueryError{Message: "Минимальная площадь больше максимальной.", Suggestion: "Например: площадь 30-50"}
+	}
+	return query, nil
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *queryParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// queryNumber is a number token like "60000", "1-2к" or "40м²"
+type queryNumber struct {
+	token  string
+	low    float64
+	high   float64
+	suffix string
+	ranged bool
+}
+
+// withSuffix takes the unit from the next token when it's written with a space, like "60 тыс".
+// A separate "м" is the metro keyword, as in "до 60000 м Сокольники"; meters are written "40м".
+func (p *queryParser) withSuffix(number queryNumber) queryNumber {
+	if suffix := p.peek(); number.suffix == "" && suffix != "м" && (thousandSuffixes[suffix] || areaSuffixes[suffix] || roomSuffixes[suffix]) {
+		number.suffix = p.next()
+	}
+	return number
+}
+
+func parseQueryNumber(token string) (queryNumber, bool) {
+	match := searchNumberPattern.FindStringSubmatch(token)
+	if match == nil {
+		return queryNumber{}, false
+	}
+
+	number := queryNumber{token: token, suffix: match[3]}
+	number.low, _ = strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	number.high = number.low
+	if match[2] != "" {
+		number.high, _ = strconv.ParseFloat(strings.Replace(match[2], ",", ".", 1), 64)
+		number.ranged = true
+	}
+	return number, true
+}
+
+func isOperator(token string) bool {
+	switch token {
+	case ">", ">=", "<", "<=", "=":
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseTerm(query *SearchQuery) error {
+	token := p.next()
+
+	if isOperator(token) {
+		return p.parseComparison(query, "", token)
+	}
+	if number, ok := parseQueryNumber(token); ok {
+		return p.parseNumber(query, number)
+	}
+
+	switch token {
+	case ",":
+		return nil
+	case "до", "от":
+		return p.parseBound(query, token)
+	case "цена", "цене", "цены":
+		return p.parseField(query, "цена", token)
+	case "этаж", "этаже", "этажа":
+		return p.parseField(query, "этаж", token)
+	case "площадь", "площадью":
+		return p.parseField(query, "площадь", token)
+	case "метро", "м", "м.", "ст.м.", "станция":
+		stations, err := p.parseNames(token, "Например: метро Сокольники")
+		query.Metro = append(query.Metro, stations...)
+		return err
+	case "район", "районе", "р-н":
+		districts, err := p.parseNames(token, "Например: район Хамовники")
+		query.Districts = append(query.Districts, districts...)
+		return err
+	case "студия", "студию", "студии":
+		query.Rooms = appendRooms(query.Rooms, 0, 0)
+		return nil
+	case "собственник", "собственника":
+		query.OwnerOnly = true
+		return nil
+	case "без":
+		if next := p.next(); next != "комиссии" && next != "комиссий" {
+			return &QueryError{Token: next, Message: fmt.Sprintf("Непонятно, без чего: «без %s».", next), Suggestion: "Можно искать объявления «без комиссии»."}
+		}
+		query.NoCommission = true
+		return nil
+	case "не":
+		switch next := p.next(); next {
+		case "первый", "первом":
+			query.NotFirstFloor = true
+		case "последний", "последнем":
+			query.NotLastFloor = true
+		default:
+			return &QueryError{Token: next, Message: fmt.Sprintf("Непонятно: «не %s».", next), Suggestion: "Можно исключить этажи: «не первый», «не последний»."}
+		}
+		if next := p.peek(); next == "этаж" || next == "этаже" {
+			p.next()
+		}
+		return nil
+	}
+
+	if searchStopWords[token] {
+		return nil
+	}
+	if keyword := closestKeyword(token); keyword != "" && (isOperator(p.peek()) || startsWithDigit(p.peek()) || keyword == "метро" || keyword == "район") {
+		return &QueryError{Token: token, Message: fmt.Sprintf("Непонятное слово «%s».", token), Suggestion: fmt.Sprintf("Возможно, вы имели в виду «%s»?", keyword)}
+	}
+	if attached := strings.IndexAny(token, "0123456789"); attached > 0 {
+		if keyword := closestKeyword(token[:attached]); keyword != "" {
+			return &QueryError{Token: token, Message: fmt.Sprintf("Непонятное слово «%s».", token), Suggestion: fmt.Sprintf("Возможно, вы имели в виду «%s %s»?", keyword, token[attached:])}
+		}
+	}
+
+	query.Words = append(query.Words, token)
+	return nil
+}
+
+// parseNumber handles a number that doesn't follow a keyword: a room count, or a range of prices or areas
+func (p *queryParser) parseNumber(query *SearchQuery, number queryNumber) error {
+	number = p.withSuffix(number)
+
+	switch {
+	case roomSuffixes[number.suffix] && number.high <= maxQueryRooms:
+		if number.low != math.Trunc(number.low) || number.high != math.Trunc(number.high) {
+			return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятное число комнат «%s».", number.token), Suggestion: "Несколько вариантов пишутся так: «1-2к» или «1к 2к»."}
+		}
+		query.Rooms = appendRooms(query.Rooms, int(number.low), int(number.high))
+		return nil
+	case areaSuffixes[number.suffix] && number.ranged && number.high > 0:
+		query.MinArea, query.MaxArea = number.low, number.high
+		return nil
+	case number.ranged:
+		low, high, ok := priceValues(number)
+		if !ok || low < minQueryPrice {
+			break
+		}
+		query.MinPrice, query.MaxPrice = low, high
+		return nil
+	}
+
+	suggestion := fmt.Sprintf("Уточните, что это: «до %[1]s», «от %[1]s», «этаж %[1]s» или «площадь %[1]s».", number.token)
+	switch {
+	case number.low >= minQueryPrice:
+		suggestion = fmt.Sprintf("Уточните цену: «до %[1]s» или «от %[1]s».", number.token)
+	case !number.ranged && number.low <= maxQueryRooms && number.suffix == "":
+		suggestion = fmt.Sprintf("Уточните: «%[1]sк» - число комнат, «этаж %[1]s» - этаж.", number.token)
+	}
+	return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятное число «%s».", number.token), Suggestion: suggestion}
+}
+
+// parseBound handles "до" and "от" followed by a price or an area
+func (p *queryParser) parseBound(query *SearchQuery, keyword string) error {
+	if p.peek() == "собственника" && keyword == "от" {
+		p.next()
+		query.OwnerOnly = true
+		return nil
+	}
+
+	number, ok := parseQueryNumber(p.peek())
+	if !ok || number.ranged {
+		return &QueryError{Token: keyword, Message: fmt.Sprintf("После «%s» нужна цена или площадь.", keyword), Suggestion: fmt.Sprintf("Например: %s 60000 или %s 40м", keyword, keyword)}
+	}
+	p.next()
+	number = p.withSuffix(number)
+
+	if number.low <= 0 {
+		return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятное число «%s».", number.token), Suggestion: fmt.Sprintf("Например: %s 60000 или %s 40м", keyword, keyword)}
+	}
+	if areaSuffixes[number.suffix] {
+		if keyword == "до" {
+			query.MaxArea = number.low
+		} else {
+			query.MinArea = number.low
+		}
+		return nil
+	}
+
+	price, _, ok := priceValues(number)
+	if !ok {
+		return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятная цена «%s».", number.token), Suggestion: fmt.Sprintf("Например: %s 60000 или %s 60 тыс", keyword, keyword)}
+	}
+	if keyword == "до" {
+		query.MaxPrice = price
+	} else {
+		query.MinPrice = price
+	}
+	return nil
+}
+
+// parseField handles "цена", "этаж" and "площадь" followed by a comparison, a number or a range
+func (p *queryParser) parseField(query *SearchQuery, field, token string) error {
+	if operator := p.peek(); isOperator(operator) {
+		p.next()
+		return p.parseComparison(query, field, operator)
+	}
+	if bound := p.peek(); bound == "до" || bound == "от" {
+		return p.parseFieldBounds(query, field)
+	}
+
+	number, ok := parseQueryNumber(p.peek())
+	if !ok {
+		return &QueryError{Token: token, Message: fmt.Sprintf("После «%s» нужно число.", token), Suggestion: fmt.Sprintf("Например: %s>3 или %s 3-10", field, field)}
+	}
+	p.next()
+	number = p.withSuffix(number)
+
+	// A single number is the least area, the highest price or the exact floor
+	low, high := number.low, number.high
+	switch {
+	case number.ranged:
+	case field == "площадь":
+		high = 0
+	case field == "цена":
+		low = 0
+	}
+	return applyBounds(query, field, number, low, high)
+}
+
+// parseFieldBounds handles "до" and "от" after a field, e.g. "цена до 60000" or "площадь от 30 до 50"
+func (p *queryParser) parseFieldBounds(query *SearchQuery, field string) error {
+	for bound := p.peek(); bound == "до" || bound == "от"; bound = p.peek() {
+		p.next()
+		number, ok := parseQueryNumber(p.peek())
+		if !ok || number.ranged {
+			return &QueryError{Token: bound, Message: fmt.Sprintf("После «%s» нужно число.", bound), Suggestion: fmt.Sprintf("Например: %s от 3 до 10", field)}
+		}
+		p.next()
+		number = p.withSuffix(number)
+
+		low, high := number.low, 0.0
+		if bound == "до" {
+			low, high = 0, number.low
+		}
+		if err := applyBounds(query, field, number, low, high); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseComparison handles an operator and a number, e.g. "этаж>3"; without a field the number's
+// suffix or size tells a price from an area
+func (p *queryParser) parseComparison(query *SearchQuery, field, operator string) error {
+	number, ok := parseQueryNumber(p.peek())
+	if !ok || number.ranged {
+		return &QueryError{Token: operator, Message: fmt.Sprintf("После «%s» нужно число.", operator), Suggestion: "Например: этаж>3 или площадь>=40"}
+	}
+	p.next()
+	number = p.withSuffix(number)
+
+	if field == "" {
+		switch {
+		case areaSuffixes[number.suffix]:
+			field = "площадь"
+		case number.low >= minQueryPrice || thousandSuffixes[number.suffix]:
+			field = "цена"
+		default:
+			return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятно, с чем сравнивать «%s%s».", operator, number.token), Suggestion: fmt.Sprintf("Например: этаж%s%s или площадь%s%s", operator, number.token, operator, number.token)}
+		}
+	}
+
+	// Integer fields turn strict comparisons into inclusive bounds
+	step := 1.0
+	if field == "площадь" {
+		step = 0
+	}
+	low, high := 0.0, 0.0
+	switch operator {
+	case ">":
+		low = number.low + step
+	case ">=":
+		low = number.low
+	case "<":
+		high = number.low - step
+	case "<=":
+		high = number.low
+	case "=":
+		low, high = number.low, number.low
+	}
+	return applyBounds(query, field, number, low, high)
+}
+
+// applyBounds sets the bounds of a field; a zero bound is left unchanged, but at least one must be set,
+// otherwise "этаж 0" would parse into a query without the floor
+func applyBounds(query *SearchQuery, field string, number queryNumber, low, high float64) error {
+	switch field {
+	case "этаж":
+		if low != math.Trunc(low) || high != math.Trunc(high) || low < 0 || high < 0 || (low == 0 && high == 0) {
+			return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятный этаж «%s».", number.token), Suggestion: "Например: этаж>3 или этаж 3-10"}
+		}
+		if low > 0 {
+			query.MinFloor = int(low)
+		}
+		if high > 0 {
+			query.MaxFloor = int(high)
+		}
+	case "площадь":
+		if low <= 0 && high <= 0 {
+			return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятная площадь «%s».", number.token), Suggestion: "Например: площадь>=40 или площадь 30-50"}
+		}
+		if low > 0 {
+			query.MinArea = low
+		}
+		if high > 0 {
+			query.MaxArea = high
+		}
+	case "цена":
+		multiplier, ok := priceMultiplier(number.suffix)
+		if !ok || (low <= 0 && high <= 0) {
+			return &QueryError{Token: number.token, Message: fmt.Sprintf("Непонятная цена «%s».", number.token), Suggestion: "Например: цена<60000 или цена 40000-60000"}
+		}
+		if low > 0 {
+			query.MinPrice = int(low * multiplier)
+		}
+		if high > 0 {
+			query.MaxPrice = int(high * multiplier)
+		}
+	}
+	return nil
+}
+
+// parseNames collects comma-separated names of stations or districts up to the next keyword or number
+func (p *queryParser) parseNames(keyword, example string) ([]string, error) {
+	var names []string
+	var words []string
+	flush := func() {
+		if len(words) > 0 {
+			names = append(names, strings.Join(words, " "))
+			words = nil
+		}
+	}
+
+	for !p.done() {
+		token := p.peek()
+		if token == "," {
+			p.next()
+			flush()
+			continue
+		}
+		if isOperator(token) || startsWithDigit(token) || isQueryKeyword(token) || p.startsField() {
+			break
+		}
+		words = append(words, p.next())
+	}
+	flush()
+
+	if len(names) == 0 {
+		return nil, &QueryError{Token: keyword, Message: fmt.Sprintf("После «%s» нужно название.", keyword), Suggestion: example}
+	}
+	return names, nil
+}
+
+// isQueryKeyword reports whether a token starts another part of the query
+func isQueryKeyword(token string) bool {
+	switch token {
+	case "до", "от", "метро", "м", "м.", "район", "районе", "р-н",
+		"студия", "студию", "студии", "собственник", "собственника", "без", "не":
+		return true
+	}
+	return false
+}
+
+// startsField reports whether the next tokens are a field with a number, like "этаж>3"; without
+// the number the word is part of a name, like in "Преображенская площадь"
+func (p *queryParser) startsField() bool {
+	switch p.peek() {
+	case "цена", "цене", "этаж", "этаже", "площадь":
+	default:
+		return false
+	}
+	if p.pos+1 >= len(p.tokens) {
+		return false
+	}
+	next := p.tokens[p.pos+1]
+	return isOperator(next) || startsWithDigit(next)
+}
+
+func startsWithDigit(token string) bool {
+	return token != "" && token[0] >= '0' && token[0] <= '9'
+}
+
+func priceMultiplier(suffix string) (float64, bool) {
+	switch {
+	case thousandSuffixes[suffix]:
+		return 1000, true
+	case rubleSuffixes[suffix]:
+		return 1, true
+	}
+	return 0, false
+}
+
+func priceValues(number queryNumber) (int, int, bool) {
+	multiplier, ok := priceMultiplier(number.suffix)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(number.low * multiplier), int(number.high * multiplier), true
+}
+
+func appendRooms(rooms []int, low, high int) []int {
+	for count := low; count <= high; count++ {
+		found := false
+		for _, existing := range rooms {
+			found = found || existing == count
+		}
+		if !found {
+			rooms = append(rooms, count)
+		}
+	}
+	sort.Ints(rooms)
+	return rooms
+}
+
+// closestKeyword returns the keyword a word is most likely a misspelling of, or "" if none is close
+func closestKeyword(word string) string {
+	length := utf8.RuneCountInString(word)
+	if length < 3 {
+		return ""
+	}
+
+	best, bestDistance := "", 0
+	for _, keyword := range searchKeywords {
+		if strings.Contains(keyword, " ") {
+			continue
+		}
+		distance := editDistance(word, keyword)
+		// One typo per four letters
+		if distance > max(1, utf8.RuneCountInString(keyword)/4) {
+			continue
+		}
+		if best == "" || distance < bestDistance {
+			best, bestDistance = keyword, distance
+		}
+	}
+	return best
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of adjacent letters
+// that turn a into b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// Match reports whether a listing satisfies the query
+func (q *SearchQuery) Match(listing *models.Listing) bool {
+	if len(q.Rooms) > 0 {
+		rooms, err := strconv.Atoi(normalizeRooms(listing.Rooms))
+		if err != nil || !containsInt(q.Rooms, rooms) {
+			return false
+		}
+	}
+
+	if (q.MinPrice > 0 || q.MaxPrice > 0) && listing.PriceValue <= 0 {
+		return false
+	}
+	if (q.MinPrice > 0 && listing.PriceValue < q.MinPrice) || (q.MaxPrice > 0 && listing.PriceValue > q.MaxPrice) {
+		return false
+	}
+
+	if q.MinArea > 0 || q.MaxArea > 0 {
+		area := listingArea(listing)
+		if area <= 0 || (q.MinArea > 0 && area < q.MinArea) || (q.MaxArea > 0 && area > q.MaxArea) {
+			return false
+		}
+	}
+
+	if q.MinFloor > 0 || q.MaxFloor > 0 || q.NotFirstFloor || q.NotLastFloor {
+		floor, total := parseFloor(listing.Floor)
+		if floor == 0 {
+			return false
+		}
+		if (q.MinFloor > 0 && floor < q.MinFloor) || (q.MaxFloor > 0 && floor > q.MaxFloor) {
+			return false
+		}
+		if (q.NotFirstFloor && floor == 1) || (q.NotLastFloor && total > 0 && floor >= total) {
+			return false
+		}
+	}
+
+	if q.NoCommission && listing.Commission > 0 {
+		return false
+	}
+	if q.OwnerOnly && !ownerAuthorTypes[strings.ToLower(listing.AuthorType)] {
+		return false
+	}
+
+	if len(q.Metro) > 0 && !containsAny(listing.Metro, q.Metro) {
+		return false
+	}
+	if len(q.Districts) > 0 && !containsAny(listing.District+" "+listing.Address, q.Districts) {
+		return false
+	}
+
+	text := strings.Join([]string{listing.Title, listing.Address, listing.Description, listing.Metro, listing.District}, " ")
+	for _, word := range q.Words {
+		if !containsAny(text, []string{word}) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterListings returns the listings matching the query in their original order
+func (q *SearchQuery) FilterListings(listings []models.Listing) []models.Listing {
+	var matched []models.Listing
+	for i := range listings {
+		if q.Match(&listings[i]) {
+			matched = append(matched, listings[i])
+		}
+	}
+	return matched
+}
+
+// String formats the query in the canonical form, which parses back into the same query
+func (q *SearchQuery) String() string {
+	var parts []string
+	parts = append(parts, q.Words...)
+
+	var rooms []string
+	for _, count := range q.Rooms {
+		if count == 0 {
+			rooms = append(rooms, "студия")
+		} else {
+			rooms = append(rooms, fmt.Sprintf("%dк", count))
+		}
+	}
+	parts = append(parts, rooms...)
+
+	if q.MinPrice > 0 {
+		parts = append(parts, fmt.Sprintf("от %d", q.MinPrice))
+	}
+	if q.MaxPrice > 0 {
+		parts = append(parts, fmt.Sprintf("до %d", q.MaxPrice))
+	}
+	if q.MinArea > 0 {
+		parts = append(parts, "площадь>="+strconv.FormatFloat(q.MinArea, 'f', -1, 64))
+	}
+	if q.MaxArea > 0 {
+		parts = append(parts, "площадь<="+strconv.FormatFloat(q.MaxArea, 'f', -1, 64))
+	}
+	if q.MinFloor > 0 {
+		parts = append(parts, fmt.Sprintf("этаж>=%d", q.MinFloor))
+	}
+	if q.MaxFloor > 0 {
+		parts = append(parts, fmt.Sprintf("этаж<=%d", q.MaxFloor))
+	}
+	if q.NotFirstFloor {
+		parts = append(parts, "не первый")
+	}
+	if q.NotLastFloor {
+		parts = append(parts, "не последний")
+	}
+	if q.NoCommission {
+		parts = append(parts, "без комиссии")
+	}
+	if q.OwnerOnly {
+		parts = append(parts, "собственник")
+	}
+	// Names run up to the next keyword, so they go last
+	if len(q.Districts) > 0 {
+		parts = append(parts, "район "+strings.Join(q.Districts, ", "))
+	}
+	if len(q.Metro) > 0 {
+		parts = append(parts, "метро "+strings.Join(q.Metro, ", "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// listingArea returns the area of a listing in square meters, 0 when unknown
+func listingArea(listing *models.Listing) float64 {
+	area, err := strconv.ParseFloat(strings.Replace(firstNumber(listing.Area), ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	return area
+}
+
+// parseFloor returns the floor and the number of floors from texts like "3/9" or "3 из 9"; zero when unknown
+func parseFloor(floor string) (current, total int) {
+	numbers := numberPattern.FindAllString(floor, 2)
+	if len(numbers) > 0 {
+		current, _ = strconv.Atoi(numbers[0])
+	}
+	if len(numbers) > 1 {
+		total, _ = strconv.Atoi(numbers[1])
+	}
+	return current, total
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether text contains any of the lower case needles, ignoring case and ё
+func containsAny(text string, needles []string) bool {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	for _, needle := range needles {
+		if strings.Contains(text, needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"telegram_bot_service/internal/services"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  services.SearchQuery
+	}{
+		{"2к до 60000 метро Сокольники этаж>3", services.SearchQuery{Rooms: []int{2}, MaxPrice: 60000, MinFloor: 4, Metro: []string{"сокольники"}}},
+		{"студия от 30000 до 45000 без комиссии", services.SearchQuery{Rooms: []int{0}, MinPrice: 30000, MaxPrice: 45000, NoCommission: true}},
+		{"1-2к площадь>=35 район Хамовники не первый", services.SearchQuery{Rooms: []int{1, 2}, MinArea: 35, Districts: []string{"хамовники"}, NotFirstFloor: true}},
+		{"3к до 120 тыс собственник", services.SearchQuery{Rooms: []int{3}, MaxPrice: 120000, OwnerOnly: true}},
+		{"40000-60000 30-50м", services.SearchQuery{MinPrice: 40000, MaxPrice: 60000, MinArea: 30, MaxArea: 50}},
+		{"от 40м", services.SearchQuery{MinArea: 40}},
+		{"площадь 40 м2", services.SearchQuery{MinArea: 40}},
+		{"метро Сокольники, Красносельская", services.SearchQuery{Metro: []string{"сокольники", "красносельская"}}},
+		{"метро Преображенская площадь", services.SearchQuery{Metro: []string{"преображенская площадь"}}},
+		{"этаж 3-10 не последний", services.SearchQuery{MinFloor: 3, MaxFloor: 10, NotLastFloor: true}},
+
+		// A separate "м" is the metro, not the unit of the number before it
+		{"до 60000 м Сокольники", services.SearchQuery{MaxPrice: 60000, Metro: []string{"сокольники"}}},
+		{"2к м Сокольники", services.SearchQuery{Rooms: []int{2}, Metro: []string{"сокольники"}}},
+
+		// Bounds after a field
+		{"цена до 60000", services.SearchQuery{MaxPrice: 60000}},
+		{"цена от 40 тыс до 60 тыс", services.SearchQuery{MinPrice: 40000, MaxPrice: 60000}},
+		{"площадь от 30 до 50", services.SearchQuery{MinArea: 30, MaxArea: 50}},
+		{"этаж от 2", services.SearchQuery{MinFloor: 2}},
+
+		// Thousands separated by spaces
+		{"до 50 000", services.SearchQuery{MaxPrice: 50000}},
+		{"от 1 200 000", services.SearchQuery{MinPrice: 1200000}},
+		{"40 000-60 000", services.SearchQuery{MinPrice: 40000, MaxPrice: 60000}},
+		{"цена<50 000 ₽", services.SearchQuery{MaxPrice: 49999}},
+
+		{"Квартира у парка", services.SearchQuery{Words: []string{"парка"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := services.ParseSearchQuery(tt.input)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		token string
+	}{
+		{"", ""},
+		{"этаж 0", "0"},
+		{"этаж<1", "1"},
+		{"цена 0", "0"},
+		{"до 0", "0"},
+		{"площадь>0", "0"},
+		{"этж>3", "этж"},
+		{"2к 5", "5"},
+		{"от 60000 до 40000", ""},
+		{"цена до", "до"},
+		{"без мебели", "мебели"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := services.ParseSearchQuery(tt.input)
+			var queryErr *services.QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("got %+v, %v, want a QueryError", query, err)
+			}
+			if queryErr.Token != tt.token {
+				t.Errorf("error token = %q, want %q", queryErr.Token, tt.token)
+			}
+		})
+	}
+}
+
+// String must parse back into the same query, saved searches are stored this way
+func TestSearchQueryStringRoundTrip(t *testing.T) {
+	inputs := []string{
+		"2к до 60000 метро Сокольники этаж>3",
+		"студия 1к от 30000 до 45000 без комиссии собственник",
+		"1-2к площадь 35.5-60 район Хамовники, Арбат не первый не последний",
+		"метро Преображенская площадь, м Сокольники",
+		"этаж 1",
+		"этаж<=5 площадь<40",
+		"цена от 40 тыс до 50 000",
+		"у парка с балконом",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			query, err := services.ParseSearchQuery(input)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q): %v", input, err)
+			}
+			formatted := query.String()
+			reparsed, err := services.ParseSearchQuery(formatted)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q): %v", formatted, err)
+			}
+			if !reflect.DeepEqual(query, reparsed) {
+				t.Errorf("%q parsed into %+v, its form %q into %+v", input, *query, formatted, *reparsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxSavedSearches caps the saved searches of one user
+	MaxSavedSearches = 10
+	// MaxSearchNameLength caps the length of a saved search name in characters
+	MaxSearchNameLength = 40
+)
+
+var (
+	ErrSearchNotFound    = errors.New("saved search not found")
+	ErrSearchNameTaken   = errors.New("saved search name is already used")
+	ErrInvalidSearchName = errors.New("invalid saved search name")
+	ErrTooManySearches   = errors.New("too many saved searches")
+)
+
+// SavedSearch is a named subscription with its own notifications
+type SavedSearch struct {
+	ID     uint
+	UserID int64
+	// Name is empty for the subscription to all listings created by /subscribe
+	Name string
+	// Query is nil for a search matching all listings
+	Query  *SearchQuery
+	Active bool
+}
+
+// Match reports whether a listing belongs to the search
+func (s *SavedSearch) Match(listing *models.Listing) bool {
+	return s.Query == nil || s.Query.Match(listing)
+}
+
+// searchSettings is the Settings JSON of a subscription
+type searchSettings struct {
+	Query string `json:"query,omitempty"`
+}
+
+type SubscriptionService struct {
+	subscriptions repository.SubscriptionRepository
+}
+
+func NewSubscriptionService(subscriptions repository.SubscriptionRepository) *SubscriptionService {
+	return &SubscriptionService{subscriptions: subscriptions}
+}
+
+// Subscribe turns on notifications about all listings, creating the default subscription or
+// resuming it; saved searches keep their own state
+func (s *SubscriptionService) Subscribe(ctx context.Context, userID int64) error {
+	subscription, err := s.defaultSubscription(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if subscription != nil {
+		subscription.IsActive = true
+		return s.subscriptions.UpdateSubscription(ctx, subscription)
+	}
+
+	return s.subscriptions.CreateSubscription(ctx, &models.Subscription{
+		UserID:   userID,
+		IsActive: true,
+		Settings: "{}",
+	})
+}
+
+// Unsubscribe pauses the default subscription; saved searches keep their own state
+func (s *SubscriptionService) Unsubscribe(ctx context.Context, userID int64) error {
+	subscription, err := s.defaultSubscription(ctx, userID)
+	if err != nil || subscription == nil {
+		return err
+	}
+	subscription.IsActive = false
+	return s.subscriptions.UpdateSubscription(ctx, subscription)
+}
+
+// defaultSubscription returns the unnamed subscription created by /subscribe, or nil if there is none
+func (s *SubscriptionService) defaultSubscription(ctx context.Context, userID int64) (*models.Subscription, error) {
+	subscriptions, err := s.subscriptions.ListUserSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		if subscriptions[i].Name == "" {
+			return &subscriptions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// IsSubscribed checks if a user has an active subscription
+func (s *SubscriptionService) IsSubscribed(ctx context.Context, userID int64) (bool, error) {
+	subscriptions, err := s.subscriptions.ListUserSubscriptions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.IsActive {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetActiveSubscriptions gets the subscriptions that receive notifications
+func (s *SubscriptionService) GetActiveSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	return s.subscriptions.ListActiveSubscriptions(ctx)
+}
+
+// CountActiveSubscriptions counts active subscriptions across users
+func (s *SubscriptionService) CountActiveSubscriptions(ctx context.Context) (int64, error) {
+	return s.subscriptions.CountActiveSubscriptions(ctx)
+}
+
+// ListSearches returns a user's named saved searches in the order they were created; the default
+// subscription of /subscribe is not one of them and doesn't count toward MaxSavedSearches
+func (s *SubscriptionService) ListSearches(ctx context.Context, userID int64) ([]SavedSearch, error) {
+	subscriptions, err := s.subscriptions.ListUserSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	named := subscriptions[:0]
+	for _, subscription := range subscriptions {
+		if subscription.Name != "" {
+			named = append(named, subscription)
+		}
+	}
+	return savedSearches(ctx, named), nil
+}
+
+// ActiveSearches returns the saved searches of all users that receive notifications
+func (s *SubscriptionService) ActiveSearches(ctx context.Context) ([]SavedSearch, error) {
+	subscriptions, err := s.subscriptions.ListActiveSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return savedSearches(ctx, subscriptions), nil
+}
+
+// SaveSearch creates an active saved search; a nil query matches all listings
+func (s *SubscriptionService) SaveSearch(ctx context.Context, userID int64, name string, query *SearchQuery) (*SavedSearch, error) {
+	existing, err := s.ListSearches(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MaxSavedSearches {
+		return nil, ErrTooManySearches
+	}
+	if name, err = checkSearchName(existing, 0, name); err != nil {
+		return nil, err
+	}
+
+	settings := searchSettings{}
+	if query != nil {
+		settings.Query = query.String()
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.Subscription{UserID: userID, Name: name, IsActive: true, Settings: string(data)}
+	if err := s.subscriptions.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return &SavedSearch{ID: subscription.ID, UserID: userID, Name: name, Query: query, Active: true}, nil
+}
+
+// RenameSearch changes the name of a user's saved search
+func (s *SubscriptionService) RenameSearch(ctx context.Context, userID int64, id uint, name string) error {
+	subscription, err := s.userSubscription(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.ListSearches(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if subscription.Name, err = checkSearchName(existing, id, name); err != nil {
+		return err
+	}
+	return s.updateSubscription(ctx, subscription)
+}
+
+// SetSearchActive pauses or resumes the notifications of one saved search
+func (s *SubscriptionService) SetSearchActive(ctx context.Context, userID int64, id uint, active bool) error {
+	subscription, err := s.userSubscription(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	subscription.IsActive = active
+	return s.updateSubscription(ctx, subscription)
+}
+
+// DeleteSearch deletes a user's saved search
+func (s *SubscriptionService) DeleteSearch(ctx context.Context, userID int64, id uint) error {
+	if _, err := s.userSubscription(ctx, userID, id); err != nil {
+		return err
+	}
+
+	err := s.subscriptions.DeleteSubscription(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrSearchNotFound
+	}
+	return err
+}
+
+// userSubscription finds a named saved search of the user; the default subscription is managed
+// only by Subscribe and Unsubscribe
+func (s *SubscriptionService) userSubscription(ctx context.Context, userID int64, id uint) (*models.Subscription, error) {
+	subscriptions, err := s.subscriptions.ListUserSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		if subscriptions[i].ID == id && subscriptions[i].Name != "" {
+			return &subscriptions[i], nil
+		}
+	}
+	return nil, ErrSearchNotFound
+}
+
+func (s *SubscriptionService) updateSubscription(ctx context.Context, subscription *models.Subscription) error {
+	err := s.subscriptions.UpdateSubscription(ctx, subscription)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrSearchNotFound
+	}
+	return err
+}
+
+// checkSearchName trims a name and checks that it fits and that no other search of the user has it
+func checkSearchName(existing []SavedSearch, id uint, name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > MaxSearchNameLength {
+		return "", ErrInvalidSearchName
+	}
+	for _, search := range existing {
+		if search.ID != id && strings.EqualFold(search.Name, name) {
+			return "", ErrSearchNameTaken
+		}
+	}
+	return name, nil
+}
+
+// savedSearches converts subscriptions to searches, skipping the ones with unreadable settings
+// so that one bad row, e.g. saved before a query grammar change, doesn't stop all notifications
+func savedSearches(ctx context.Context, subscriptions []models.Subscription) []SavedSearch {
+	searches := make([]SavedSearch, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		search, err := savedSearch(subscription)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"subscription_id": subscription.ID,
+				"user_id":         subscription.UserID,
+			}).Warn("Skipping saved search with invalid settings")
+			continue
+		}
+		searches = append(searches, search)
+	}
+	return searches
+}
+
+func savedSearch(subscription models.Subscription) (SavedSearch, error) {
+	search := SavedSearch{ID: subscription.ID, UserID: subscription.UserID, Name: subscription.Name, Active: subscription.IsActive}
+
+	var settings searchSettings
+	if subscription.Settings != "" {
+		if err := json.Unmarshal([]byte(subscription.Settings), &settings); err != nil {
+			return search, fmt.Errorf("settings: %w", err)
+		}
+	}
+	if settings.Query != "" {
+		query, err := ParseSearchQuery(settings.Query)
+		if err != nil {
+			return search, fmt.Errorf("query %q: %w", settings.Query, err)
+		}
+		search.Query = query
+	}
+	return search, nil
+}
@@ -0,0 +1,146 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"telegram_bot_service/internal/models"
+	"telegram_bot_service/internal/repository"
+	"telegram_bot_service/internal/services"
+)
+
+func newSubscriptionService(t *testing.T) (*services.SubscriptionService, repository.SubscriptionRepository) {
+	t.Helper()
+
+	users := repository.NewMemoryUserRepository()
+	if err := users.CreateUser(context.Background(), &models.User{ID: 42, IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	subscriptions := repository.NewMemorySubscriptionRepository(users)
+	return services.NewSubscriptionService(subscriptions), subscriptions
+}
+
+func TestSubscribeLeavesSavedSearchesAlone(t *testing.T) {
+	ctx := context.Background()
+	service, subscriptions := newSubscriptionService(t)
+	states := func() map[string]bool {
+		t.Helper()
+		stored, err := subscriptions.ListUserSubscriptions(ctx, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		active := make(map[string]bool)
+		for _, subscription := range stored {
+			active[subscription.Name] = subscription.IsActive
+		}
+		return active
+	}
+
+	query, err := services.ParseSearchQuery("2к до 60000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := service.SaveSearch(ctx, 42, "Для себя", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default subscription is created even though the user has a saved search
+	if err := service.Subscribe(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	if got := states(); len(got) != 2 || !got[""] {
+		t.Fatalf("subscriptions after /subscribe = %v", got)
+	}
+
+	if err := service.SetSearchActive(ctx, 42, saved.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Unsubscribe(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Subscribe(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := states(); len(got) != 2 || got["Для себя"] || !got[""] {
+		t.Errorf("/subscribe changed a paused saved search: %v", got)
+	}
+
+	if err := service.Unsubscribe(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetSearchActive(ctx, 42, saved.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	active, err := service.ActiveSearches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].ID != saved.ID {
+		t.Errorf("/unsubscribe paused a saved search: %+v", active)
+	}
+}
+
+func TestDefaultSubscriptionIsNotASavedSearch(t *testing.T) {
+	ctx := context.Background()
+	service, subscriptions := newSubscriptionService(t)
+
+	if err := service.Subscribe(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := subscriptions.ListUserSubscriptions(ctx, 42)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("subscriptions after /subscribe: %+v, %v", stored, err)
+	}
+	defaultID := stored[0].ID
+
+	if searches, err := service.ListSearches(ctx, 42); err != nil || len(searches) != 0 {
+		t.Errorf("ListSearches = %+v, %v, want no searches", searches, err)
+	}
+
+	// The default subscription leaves room for MaxSavedSearches named ones
+	for i := 0; i < services.MaxSavedSearches; i++ {
+		if _, err := service.SaveSearch(ctx, 42, fmt.Sprintf("Поиск %d", i+1), nil); err != nil {
+			t.Fatalf("saving search %d: %v", i+1, err)
+		}
+	}
+
+	if err := service.RenameSearch(ctx, 42, defaultID, "Все"); !errors.Is(err, services.ErrSearchNotFound) {
+		t.Errorf("RenameSearch of the default subscription: %v, want ErrSearchNotFound", err)
+	}
+	if err := service.SetSearchActive(ctx, 42, defaultID, false); !errors.Is(err, services.ErrSearchNotFound) {
+		t.Errorf("SetSearchActive of the default subscription: %v, want ErrSearchNotFound", err)
+	}
+	if err := service.DeleteSearch(ctx, 42, defaultID); !errors.Is(err, services.ErrSearchNotFound) {
+		t.Errorf("DeleteSearch of the default subscription: %v, want ErrSearchNotFound", err)
+	}
+	if subscribed, err := service.IsSubscribed(ctx, 42); err != nil || !subscribed {
+		t.Errorf("IsSubscribed = %v, %v after rejected changes", subscribed, err)
+	}
+}
+
+func TestActiveSearchesSkipInvalidQueries(t *testing.T) {
+	ctx := context.Background()
+	service, subscriptions := newSubscriptionService(t)
+
+	for _, subscription := range []models.Subscription{
+		{UserID: 42, Name: "Сломанный", IsActive: true, Settings: `{"query":"этаж 0"}`},
+		{UserID: 42, Name: "Не JSON", IsActive: true, Settings: `query`},
+		{UserID: 42, Name: "Для себя", IsActive: true, Settings: `{"query":"2к"}`},
+	} {
+		if err := subscriptions.CreateSubscription(ctx, &subscription); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active, err := service.ActiveSearches(ctx)
+	if err != nil {
+		t.Fatalf("one invalid search broke all of them: %v", err)
+	}
+	if len(active) != 1 || active[0].Name != "Для себя" || active[0].Query == nil {
+		t.Errorf("active searches = %+v", active)
+	}
+}
@@ -1,70 +1,130 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"telegram_bot_service/internal/models"
-
-	"gorm.io/gorm"
+	"telegram_bot_service/internal/repository"
+	"time"
 )
 
 type UserService struct {
-	db *gorm.DB
+	users repository.UserRepository
+
+	mu       sync.RWMutex
+	adminIDs map[int64]bool
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(users repository.UserRepository, adminIDs []int64) *UserService {
+	s := &UserService{users: users}
+	s.SetAdminIDs(adminIDs)
+	return s
 }
 
-// CreateOrUpdateUser creates or updates a user
-func (s *UserService) CreateOrUpdateUser(userID int64, username, firstName, lastName string) (*models.User, error) {
-	user := &models.User{
-		ID:        userID,
-		Username:  username,
-		FirstName: firstName,
-		LastName:  lastName,
-		IsActive:  true,
+// SetAdminIDs replaces the list of admins, e.g. after a config reload
+func (s *UserService) SetAdminIDs(adminIDs []int64) {
+	admins := make(map[int64]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
 	}
 
-	result := s.db.Where("id = ?", userID).First(user)
-	if result.Error == gorm.ErrRecordNotFound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.adminIDs = admins
+}
+
+// CreateOrUpdateUser creates or updates a user
+func (s *UserService) CreateOrUpdateUser(ctx context.Context, userID int64, username, firstName, lastName string) (*models.User, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
 		// Create new user
-		if err := s.db.Create(user).Error; err != nil {
-			return nil, err
+		user = &models.User{
+			ID:        userID,
+			Username:  username,
+			FirstName: firstName,
+			LastName:  lastName,
+			IsActive:  true,
+			IsAdmin:   s.IsAdmin(userID),
 		}
-	} else if result.Error != nil {
-		return nil, result.Error
-	} else {
-		// Update existing user
-		user.Username = username
-		user.FirstName = firstName
-		user.LastName = lastName
-		user.IsActive = true
-		if err := s.db.Save(user).Error; err != nil {
+		if err := s.users.CreateUser(ctx, user); err != nil {
 			return nil, err
 		}
+		return user, nil
+	} else if err != nil {
+		return nil, err
 	}
 
+	// Update existing user; IsActive is left alone so a user deactivated by an admin stays deactivated
+	user.Username = username
+	user.FirstName = firstName
+	user.LastName = lastName
+	user.IsAdmin = s.IsAdmin(userID)
+	if err := s.users.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// A user writing to the bot has unblocked it
+	if user.BlockedAt != nil {
+		if err := s.users.SetUserBlockedAt(ctx, userID, nil); err != nil {
+			return nil, err
+		}
+		user.BlockedAt = nil
+	}
 	return user, nil
 }
 
-// GetUser gets a user by ID
-func (s *UserService) GetUser(userID int64) (*models.User, error) {
-	var user models.User
-	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
-		return nil, err
+// ClearBlocked makes a user who blocked the bot reachable again, e.g. when they press a button
+func (s *UserService) ClearBlocked(ctx context.Context, userID int64) error {
+	user, err := s.users.GetUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
 	}
-	return &user, nil
+	if err != nil || user.BlockedAt == nil {
+		return err
+	}
+	return s.users.SetUserBlockedAt(ctx, userID, nil)
+}
+
+// GetUser gets a user by ID
+func (s *UserService) GetUser(ctx context.Context, userID int64) (*models.User, error) {
+	return s.users.GetUser(ctx, userID)
 }
 
 // GetAllActiveUsers gets all active users
-func (s *UserService) GetAllActiveUsers() ([]models.User, error) {
-	var users []models.User
-	if err := s.db.Where("is_active = ?", true).Find(&users).Error; err != nil {
-		return nil, err
-	}
-	return users, nil
+func (s *UserService) GetAllActiveUsers(ctx context.Context) ([]models.User, error) {
+	return s.users.ListActiveUsers(ctx)
+}
+
+// DeactivateUser deactivates a user; only ActivateUser brings them back
+func (s *UserService) DeactivateUser(ctx context.Context, userID int64) error {
+	return s.users.SetUserActive(ctx, userID, false)
+}
+
+// MarkBlocked records that the user blocked the bot; they get no messages until they write again
+func (s *UserService) MarkBlocked(ctx context.Context, userID int64) error {
+	now := time.Now()
+	return s.users.SetUserBlockedAt(ctx, userID, &now)
+}
+
+// ActivateUser reverses DeactivateUser
+func (s *UserService) ActivateUser(ctx context.Context, userID int64) error {
+	return s.users.SetUserActive(ctx, userID, true)
+}
+
+// IsAdmin checks if a user is listed as an administrator
+func (s *UserService) IsAdmin(userID int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.adminIDs[userID]
+}
+
+// ListUsers gets users ordered by registration date, newest first
+func (s *UserService) ListUsers(ctx context.Context, limit int) ([]models.User, error) {
+	return s.users.ListUsers(ctx, limit)
 }
 
-// DeactivateUser deactivates a user
-func (s *UserService) DeactivateUser(userID int64) error {
-	return s.db.Model(&models.User{}).Where("id = ?", userID).Update("is_active", false).Error
+// CountUsers counts all users and active users
+func (s *UserService) CountUsers(ctx context.Context) (total int64, active int64, err error) {
+	return s.users.CountUsers(ctx)
 }
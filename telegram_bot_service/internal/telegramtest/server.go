@@ -0,0 +1,346 @@
+// Package telegramtest provides a fake Telegram Bot API server for end-to-end runs of the bot.
+// Point tgbotapi at it with tgbotapi.NewBotAPIWithAPIEndpoint(token, server.Endpoint()).
+package telegramtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxPollTimeout caps long polling so a stopped test never waits for a full Telegram timeout
+const maxPollTimeout = 2 * time.Second
+
+// Sent is a request the bot made to the fake server
+type Sent struct {
+	Method    string
+	ChatID    int64
+	MessageID int
+	Text      string
+	Params    map[string]string
+	Markup    *tgbotapi.InlineKeyboardMarkup
+}
+
+// Buttons returns the callback data of all inline keyboard buttons
+func (s Sent) Buttons() []string {
+	if s.Markup == nil {
+		return nil
+	}
+
+	var data []string
+	for _, row := range s.Markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data = append(data, *button.CallbackData)
+			}
+		}
+	}
+	return data
+}
+
+// failure is an API error returned for every request to a chat
+type failure struct {
+	code        int
+	description string
+}
+
+// Server is a fake Bot API that queues scripted updates and records what the bot sends
+type Server struct {
+	Bot tgbotapi.User
+
+	httpServer *httptest.Server
+
+	mu            sync.Mutex
+	updates       []tgbotapi.Update
+	nextUpdateID  int
+	nextMessageID int
+	sent          []Sent
+	failures      map[int64]failure
+	// changed is closed and replaced whenever updates or sent messages are added
+	changed chan struct{}
+}
+
+// NewServer starts a fake Bot API server; call Close when done
+func NewServer() *Server {
+	s := &Server{
+		Bot:      tgbotapi.User{ID: 1, IsBot: true, FirstName: "Test Bot", UserName: "test_bot"},
+		failures: make(map[int64]failure),
+		changed:  make(chan struct{}),
+	}
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint returns the API endpoint format expected by tgbotapi.NewBotAPIWithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.httpServer.URL + "/bot%s/%s"
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// SendMessage queues a private chat message from userID; text starting with "/" is a command
+func (s *Server) SendMessage(userID int64, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMessageID++
+	message := &tgbotapi.Message{
+		MessageID: s.nextMessageID,
+		From:      testUser(userID),
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command := strings.SplitN(text, " ", 2)[0]
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+
+	s.queue(tgbotapi.Update{Message: message})
+}
+
+// PressButton queues a press of an inline button with callback data under messageID
+func (s *Server) PressButton(userID int64, messageID int, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:   fmt.Sprintf("callback-%d", s.nextUpdateID+1),
+		From: testUser(userID),
+		Message: &tgbotapi.Message{
+			MessageID: messageID,
+			Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		},
+		Data: data,
+	}})
+}
+
+// FailChat makes every request to chatID fail, e.g. with 403 "Forbidden: bot was blocked by the user"
+func (s *Server) FailChat(chatID int64, code int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[chatID] = failure{code: code, description: description}
+}
+
+// RestoreChat undoes FailChat, e.g. when the user unblocks the bot
+func (s *Server) RestoreChat(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, chatID)
+}
+
+// Sent returns all requests the bot made except polling, in order
+func (s *Server) Sent() []Sent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Sent(nil), s.sent...)
+}
+
+// SentTo returns the messages sent or edited in chatID
+func (s *Server) SentTo(chatID int64) []Sent {
+	var result []Sent
+	for _, sent := range s.Sent() {
+		if sent.ChatID == chatID && sent.Method != "answerCallbackQuery" {
+			result = append(result, sent)
+		}
+	}
+	return result
+}
+
+// WaitForSent waits until at least n messages were sent to chatID and returns them
+func (s *Server) WaitForSent(chatID int64, n int, timeout time.Duration) ([]Sent, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		if sent := s.SentTo(chatID); len(sent) >= n {
+			return sent, nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return s.SentTo(chatID), fmt.Errorf("timed out waiting for %d messages to chat %d", n, chatID)
+		}
+	}
+}
+
+// queue adds an update; callers hold s.mu
+func (s *Server) queue(update tgbotapi.Update) {
+	s.nextUpdateID++
+	update.UpdateID = s.nextUpdateID
+	s.updates = append(s.updates, update)
+	s.notify()
+}
+
+// notify wakes up long polls and waiters; callers hold s.mu
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// Paths look like /bot<token>/<method>
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "bot") {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	method := parts[1]
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+	params := make(map[string]string, len(r.Form))
+	for key := range r.Form {
+		params[key] = r.Form.Get(key)
+	}
+
+	switch method {
+	case "getMe":
+		writeResult(w, s.Bot)
+	case "getUpdates":
+		writeResult(w, s.getUpdates(params))
+	case "deleteWebhook", "setWebhook":
+		writeResult(w, true)
+	case "sendMessage", "editMessageText", "answerCallbackQuery", "answerInlineQuery", "sendMediaGroup", "sendPhoto":
+		s.record(w, method, params)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method not found")
+	}
+}
+
+func (s *Server) getUpdates(params map[string]string) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(params["offset"])
+	timeout, _ := strconv.Atoi(params["timeout"])
+	wait := time.Duration(timeout) * time.Second
+	if wait > maxPollTimeout {
+		wait = maxPollTimeout
+	}
+	deadline := time.After(wait)
+
+	for {
+		s.mu.Lock()
+		// Like Telegram, an offset confirms all earlier updates
+		var pending []tgbotapi.Update
+		kept := s.updates[:0]
+		for _, update := range s.updates {
+			if update.UpdateID >= offset {
+				kept = append(kept, update)
+				pending = append(pending, update)
+			}
+		}
+		s.updates = kept
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(pending) > 0 {
+			return pending
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return []tgbotapi.Update{}
+		}
+	}
+}
+
+func (s *Server) record(w http.ResponseWriter, method string, params map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+	if failure, ok := s.failures[chatID]; ok && method != "answerCallbackQuery" {
+		writeError(w, failure.code, failure.description)
+		return
+	}
+
+	sent := Sent{Method: method, ChatID: chatID, Text: params["text"], Params: params}
+	if markup := params["reply_markup"]; markup != "" {
+		var keyboard tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(markup), &keyboard); err == nil && keyboard.InlineKeyboard != nil {
+			sent.Markup = &keyboard
+		}
+	}
+
+	var result interface{}
+	switch method {
+	case "sendMessage":
+		s.nextMessageID++
+		sent.MessageID = s.nextMessageID
+		result = s.botMessage(sent)
+	case "sendPhoto":
+		s.nextMessageID++
+		sent.MessageID = s.nextMessageID
+		sent.Text = params["caption"]
+		result = s.botMessage(sent)
+	case "editMessageText":
+		sent.MessageID, _ = strconv.Atoi(params["message_id"])
+		result = s.botMessage(sent)
+	case "answerCallbackQuery", "answerInlineQuery":
+		result = true
+	case "sendMediaGroup":
+		var media []json.RawMessage
+		_ = json.Unmarshal([]byte(params["media"]), &media)
+		messages := make([]tgbotapi.Message, 0, len(media))
+		for range media {
+			s.nextMessageID++
+			sent.MessageID = s.nextMessageID
+			messages = append(messages, s.botMessage(sent))
+		}
+		result = messages
+	}
+
+	s.sent = append(s.sent, sent)
+	s.notify()
+	writeResult(w, result)
+}
+
+func (s *Server) botMessage(sent Sent) tgbotapi.Message {
+	return tgbotapi.Message{
+		MessageID: sent.MessageID,
+		From:      &s.Bot,
+		Chat:      &tgbotapi.Chat{ID: sent.ChatID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      sent.Text,
+	}
+}
+
+func testUser(userID int64) *tgbotapi.User {
+	return &tgbotapi.User{ID: userID, FirstName: "Test", UserName: fmt.Sprintf("user%d", userID)}
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: code, Description: description})
+}
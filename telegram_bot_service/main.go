@@ -1,75 +1,152 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"telegram_bot_service/internal/bot"
 	"telegram_bot_service/internal/config"
 	"telegram_bot_service/internal/database"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/repository"
 	"telegram_bot_service/internal/services"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
+const usage = `usage: telegram_bot_service [--config file] [--print-config] [command]
+
+commands:
+  serve                                run the bot (default)
+  migrate up | down [steps] | status   manage the database schema
+  users list [--all] | deactivate <id>... | activate <id>... | export [--format csv|json]
+  favorites export <user> [--format csv|json]
+  broadcast --file msg.md [--dry-run]  send a Markdown message to all active users
+  check-parser                         check that the parser is up and returns valid listings
+  notify --once                        fetch listings and print what subscribers would be notified about
+`
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		logrus.Warn("No .env file found")
 	}
 
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file; environment variables override it")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage+"\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	// Initialize config
-	cfg := config.New()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
-	// Setup logging
-	setupLogging(cfg.LogLevel)
+	if *printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		fmt.Print(string(out))
+		return
+	}
 
-	// Initialize database
-	db, err := database.Initialize(cfg.DatabasePath)
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	command, args := "serve", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
 
-	// Initialize services
-	cianService := services.NewCianService(cfg.CianAPIURL)
-	userService := services.NewUserService(db)
-	favoriteService := services.NewFavoriteService(db)
+	// Setup logging; other commands keep stdout for their output
+	logging.Setup(cfg.LogLevel, cfg.LogFormat)
+	if command != "serve" {
+		logrus.SetOutput(os.Stderr)
+	}
 
-	// Initialize and start bot
-	telegramBot, err := bot.New(cfg.TelegramToken, cianService, userService, favoriteService)
+	switch command {
+	case "serve":
+		err = runServe(cfg, *configPath)
+	case "migrate":
+		err = runMigrate(cfg, args)
+	case "users":
+		err = runUsers(cfg, args)
+	case "favorites":
+		err = runFavorites(cfg, args)
+	case "broadcast":
+		err = runBroadcast(cfg, args)
+	case "check-parser":
+		err = runCheckParser(cfg, args)
+	case "notify":
+		err = runNotify(cfg, args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
 	if err != nil {
-		log.Fatalf("Failed to create bot: %v", err)
+		log.Fatalf("%s: %v", command, err)
 	}
+}
 
-	// Start health check server if enabled
-	if cfg.HealthCheckEnabled {
-		healthServer := bot.NewHealthServer(telegramBot, cfg.HealthCheckPort)
-		healthServer.Start()
+// app holds the database and services shared by the commands
+type app struct {
+	db            *gorm.DB
+	cian          *services.CianService
+	users         *services.UserService
+	favorites     *services.FavoriteService
+	subscriptions *services.SubscriptionService
+	access        *services.AccessService
+	history       *services.ListingHistoryService
+	ranking       *services.RankingService
+	market        *services.MarketService
+}
+
+// newApp opens the database, applying pending migrations, and creates the services
+func newApp(cfg *config.Config) (*app, error) {
+	db, err := database.Initialize(databaseOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("initialize database: %w", err)
 	}
 
-	logrus.Info("Starting Telegram bot...")
-	if err := telegramBot.Start(); err != nil {
-		log.Fatalf("Bot error: %v", err)
+	cianService := services.NewCianService(cfg.CianAPIURL)
+	users := repository.NewGormUserRepository(db)
+	listings := repository.NewGormListingRepository(db)
+	market := services.NewMarketService(listings)
+	var photos *services.PhotoHasher
+	if cfg.DedupPhotoHash {
+		photos = services.NewPhotoHasher()
 	}
+	return &app{
+		db:            db,
+		cian:          cianService,
+		users:         services.NewUserService(users, cfg.AdminIDs),
+		favorites:     services.NewFavoriteService(repository.NewGormFavoriteRepository(db)),
+		subscriptions: services.NewSubscriptionService(repository.NewGormSubscriptionRepository(db)),
+		access:        services.NewAccessService(repository.NewGormAccessRepository(db), cfg.AccessMode, cfg.AllowedIDs, cfg.AdminIDs),
+		history:       services.NewListingHistoryService(cianService, listings, photos),
+		ranking:       services.NewRankingService(users, listings, market),
+		market:        market,
+	}, nil
 }
 
-func setupLogging(level string) {
-	logrus.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
-
-	switch level {
-	case "debug":
-		logrus.SetLevel(logrus.DebugLevel)
-	case "info":
-		logrus.SetLevel(logrus.InfoLevel)
-	case "warn":
-		logrus.SetLevel(logrus.WarnLevel)
-	case "error":
-		logrus.SetLevel(logrus.ErrorLevel)
-	default:
-		logrus.SetLevel(logrus.InfoLevel)
-	}
+// newBot connects to Telegram with the app's services
+func (a *app) newBot(cfg *config.Config, options bot.Options) (*bot.Bot, error) {
+	return bot.New(cfg.TelegramToken, a.cian, a.users, a.favorites, a.subscriptions, a.access, options)
+}
 
-	logrus.SetOutput(os.Stdout)
+func databaseOptions(cfg *config.Config) database.Options {
+	return database.Options{
+		Driver:          cfg.DatabaseDriver,
+		URL:             cfg.DatabaseURL,
+		MaxOpenConns:    cfg.DatabaseMaxOpenConns,
+		MaxIdleConns:    cfg.DatabaseMaxIdleConns,
+		ConnMaxLifetime: cfg.DatabaseConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DatabaseConnMaxIdleTime,
+		BusyTimeout:     cfg.DatabaseBusyTimeout,
+	}
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"telegram_bot_service/internal/config"
+)
+
+const notifyUsage = "usage: telegram_bot_service notify --once"
+
+// runNotify implements the notify subcommand: one check whose result is printed instead of sent.
+// The history is left untouched, so the next check of the running bot still sees the same changes.
+func runNotify(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("notify", flag.ContinueOnError)
+	once := flags.Bool("once", false, "run a single check")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	// Periodic checks are run by serve
+	if !*once || flags.NArg() > 0 {
+		return errors.New(notifyUsage)
+	}
+
+	app, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	diff, err := app.history.Poll(ctx)
+	if err != nil {
+		return err
+	}
+	searches, err := app.subscriptions.ActiveSearches(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("fetched %d listings\n", len(diff.Listings))
+	if diff.Baseline {
+		fmt.Println("the history is empty: the first check records all listings without notifying")
+		return nil
+	}
+
+	fmt.Printf("new listings: %d\n", len(diff.New))
+	for _, listing := range diff.New {
+		fmt.Printf("  + %s  %s  %s  %s\n", listing.ID, listing.Price, listing.Title, listing.URL)
+		for _, duplicate := range diff.Duplicates[listing.ID] {
+			fmt.Printf("      = %s  %s  %s  %s\n", duplicate.ID, duplicate.Price, duplicate.Title, duplicate.URL)
+		}
+	}
+	fmt.Printf("reposts of known listings: %d\n", len(diff.Reposts))
+	for _, repost := range diff.Reposts {
+		fmt.Printf("  = %s  (same flat as %s)  %s  %s\n", repost.Listing.ID, repost.CanonicalID, repost.Listing.Price, repost.Listing.Title)
+	}
+	fmt.Printf("price changes: %d\n", len(diff.PriceChanges))
+	for _, change := range diff.PriceChanges {
+		fmt.Printf("  ~ %s  %s -> %s  %s\n", change.Listing.ID, change.OldPrice, change.Listing.Price, change.Listing.Title)
+	}
+
+	if diff.Empty() {
+		fmt.Println("nothing to notify about")
+		return nil
+	}
+
+	recipients := make(map[int64]bool)
+	for _, search := range searches {
+		matches := 0
+		for i := range diff.New {
+			if search.Match(&diff.New[i]) {
+				matches++
+			}
+		}
+		if matches > 0 {
+			recipients[search.UserID] = true
+		}
+		if search.Query != nil {
+			fmt.Printf("  search %q of user %d (%s): %d listings\n", search.Name, search.UserID, search.Query, matches)
+		}
+	}
+	fmt.Printf("would notify %d subscribers; nothing was sent or saved\n", len(recipients))
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"telegram_bot_service/internal/bot"
+	"telegram_bot_service/internal/config"
+	"telegram_bot_service/internal/database"
+	"telegram_bot_service/internal/health"
+	"telegram_bot_service/internal/logging"
+	"telegram_bot_service/internal/tracing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// shutdownTimeout bounds how long the HTTP server waits for requests in progress on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// runServe implements the serve subcommand: it runs the bot until SIGINT or SIGTERM
+func runServe(cfg *config.Config, configPath string) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	// Setup tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.TracingEndpoint)
+	if err != nil {
+		return fmt.Errorf("initialize tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to flush traces")
+		}
+	}()
+
+	// Initialize database and services
+	app, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+	if err := tracing.InstrumentGORM(app.db); err != nil {
+		return fmt.Errorf("instrument database: %w", err)
+	}
+
+	// Schedule database backups
+	backups := database.NewBackupScheduler(app.db, cfg.BackupDir, cfg.BackupKeep, cfg.BackupInterval)
+	backups.Start()
+
+	botOptions := bot.Options{
+		RateLimits:    rateLimits(cfg),
+		Workers:       cfg.WorkerPoolSize,
+		QueueSize:     cfg.WorkerQueueSize,
+		Mode:          cfg.BotMode,
+		WebhookURL:    cfg.WebhookURL,
+		WebhookPath:   cfg.WebhookPath,
+		WebhookSecret: cfg.WebhookSecret,
+		Backups:       backups,
+		History:       app.history,
+		CheckInterval: cfg.CheckInterval,
+		Ranking:       app.ranking,
+		Market:        app.market,
+		APIEndpoint:   cfg.TelegramAPIURL,
+		Messages:      messages(cfg),
+	}
+
+	// Initialize and start bot
+	telegramBot, err := app.newBot(cfg, botOptions)
+	if err != nil {
+		return fmt.Errorf("create bot: %w", err)
+	}
+
+	// Start health check server if enabled; webhook mode always needs it to receive updates
+	var prober *health.Prober
+	var healthServer *bot.HealthServer
+	if cfg.HealthCheckEnabled || cfg.BotMode == bot.ModeWebhook {
+		prober = health.NewProber(cfg.HealthInterval, cfg.HealthTimeout)
+		prober.Register("telegram_bot", true, telegramBot.Ping)
+		prober.Register("database", true, func(ctx context.Context) error {
+			return database.Ping(ctx, app.db)
+		})
+		prober.Register("cian_api", false, app.cian.HealthCheck)
+		prober.Start()
+
+		healthServer = bot.NewHealthServer(telegramBot, cfg.HealthCheckPort, prober)
+		if cfg.BotMode == bot.ModeWebhook {
+			healthServer.Handle(cfg.WebhookPath, telegramBot.WebhookHandler())
+		}
+		healthServer.Start()
+	}
+
+	// Apply safe settings from the config file without a restart
+	watcher := config.NewWatcher(configPath, cfg, func(next *config.Config) {
+		logging.SetLevel(next.LogLevel)
+		app.users.SetAdminIDs(next.AdminIDs)
+		app.access.SetPolicy(next.AccessMode, next.AllowedIDs, next.AdminIDs)
+		telegramBot.SetRateLimits(rateLimits(next))
+		telegramBot.SetMessages(messages(next))
+		telegramBot.SetCheckInterval(next.CheckInterval)
+		if prober != nil {
+			prober.SetInterval(next.HealthInterval, next.HealthTimeout)
+		}
+	})
+	watcher.Start()
+
+	// Stop gracefully on SIGINT/SIGTERM
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		logrus.Info("Shutting down...")
+		watcher.Stop()
+		backups.Stop()
+		// The server goes first, so no webhook update arrives after the bot stops taking them
+		if healthServer != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			if err := healthServer.Shutdown(ctx); err != nil {
+				logrus.WithError(err).Warn("Failed to shut down health server")
+			}
+			cancel()
+			prober.Stop()
+		}
+		telegramBot.Stop()
+	}()
+
+	logrus.Info("Starting Telegram bot...")
+	return telegramBot.Start()
+}
+
+func rateLimits(cfg *config.Config) bot.RateLimits {
+	return bot.RateLimits{
+		Messages:              cfg.RateLimitMessages,
+		Callbacks:             cfg.RateLimitCallbacks,
+		Window:                cfg.RateLimitWindow,
+		RefreshCooldown:       cfg.RefreshCooldown,
+		RefreshGlobalCooldown: cfg.RefreshGlobalCooldown,
+	}
+}
+
+func messages(cfg *config.Config) bot.Messages {
+	return bot.Messages{
+		Welcome: cfg.WelcomeMessage,
+		Help:    cfg.HelpMessage,
+	}
+}
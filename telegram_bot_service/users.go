@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"telegram_bot_service/internal/config"
+	"telegram_bot_service/internal/models"
+	"text/tabwriter"
+	"time"
+)
+
+const usersUsage = "usage: telegram_bot_service users list [--all] | deactivate <id>... | activate <id>... | export [--format csv|json]"
+
+// usersListLimit is how many of the newest users users list shows without --all
+const usersListLimit = 50
+
+// runUsers implements the users subcommand
+func runUsers(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usersUsage)
+	}
+
+	flags := flag.NewFlagSet("users "+args[0], flag.ContinueOnError)
+	all := flags.Bool("all", false, "list all users instead of the newest ones")
+	format := flags.String("format", "csv", "export format: csv or json")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	app, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		limit := usersListLimit
+		if *all {
+			limit = 0
+		}
+		users, err := app.users.ListUsers(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSERNAME\tNAME\tACTIVE\tADMIN\tCREATED AT")
+		for _, user := range users {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%t\t%s\n", user.ID, user.Username, fullName(user),
+				user.IsActive, app.users.IsAdmin(user.ID), user.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+	case "deactivate", "activate":
+		if flags.NArg() == 0 {
+			return errors.New(usersUsage)
+		}
+		setActive := app.users.DeactivateUser
+		if args[0] == "activate" {
+			setActive = app.users.ActivateUser
+		}
+		for _, arg := range flags.Args() {
+			userID, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid user ID %q", arg)
+			}
+			if err := setActive(ctx, userID); err != nil {
+				return fmt.Errorf("%s %d: %w", args[0], userID, err)
+			}
+			fmt.Printf("%sd %d\n", args[0], userID)
+		}
+		return nil
+	case "export":
+		users, err := app.users.ListUsers(ctx, 0)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(users))
+		for _, user := range users {
+			rows = append(rows, []string{
+				strconv.FormatInt(user.ID, 10), user.Username, user.FirstName, user.LastName,
+				strconv.FormatBool(user.IsActive), strconv.FormatBool(user.IsAdmin), user.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		header := []string{"id", "username", "first_name", "last_name", "is_active", "is_admin", "created_at"}
+		return writeExport(*format, header, rows, users)
+	default:
+		return errors.New(usersUsage)
+	}
+}
+
+func fullName(user models.User) string {
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}